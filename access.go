@@ -1,11 +1,18 @@
 package locatorars
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"maps"
 	"net/http"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // AccessResponse представляет ответ от сервиса проверки прав доступа
@@ -15,13 +22,50 @@ type AccessResponse struct {
 	Entity  string                 `json:"entity"`
 	Message string                 `json:"message,omitempty"`
 	User    map[string]interface{} `json:"user,omitempty"`
+
+	// confirmedAt время получения решения от сервиса. Сохраняется в копиях из кэша
+	// и хранилища последних подтвержденных решений, чтобы их возраст не сбрасывался
+	confirmedAt time.Time
+}
+
+// confirmed возвращает время получения решения от сервиса. Для решений других
+// реализаций AccessChecker оно неизвестно, и решение считается полученным сейчас
+func (r AccessResponse) confirmed() time.Time {
+	if r.confirmedAt.IsZero() {
+		return time.Now()
+	}
+	return r.confirmedAt
 }
 
 // AccessClient клиент для проверки прав доступа
 type AccessClient struct {
-	config Config
-	client *http.Client
-	logger Logger
+	config  Config
+	client  *http.Client
+	logger  Logger
+	metrics Metrics
+	cache   *decisionCache
+	lkg     *lastKnownGood
+
+	// flights объединяет одновременные одинаковые проверки, если это не отключено DisableCoalescing
+	flights *flightGroup
+
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	retry   retryPolicy
+	breaker *circuitBreaker
+
+	// local локальная политика для режимов ModeLocal и ModeLocalFirst
+	// и ошибка ее загрузки
+	local    *LocalEvaluator
+	localErr error
+
+	// batchUnsupported устанавливается, если сервис не поддерживает пакетный эндпоинт
+	batchUnsupported atomic.Bool
+
+	// initErr ошибка создания HTTP-клиента (например, некорректные TLS-настройки),
+	// возвращается при каждой проверке доступа
+	initErr error
 }
 
 // NewAccessClient создает новый клиент для проверки прав доступа
@@ -33,17 +77,267 @@ func NewAccessClient(config Config) *AccessClient {
 		logger = NewDefaultLogger(config.LogLevel)
 	}
 
+	var cache *decisionCache
+	if config.Cache.Enabled {
+		cache = newDecisionCache(config.Cache)
+	}
+
+	var lkg *lastKnownGood
+	if config.LastKnownGood.Enabled {
+		lkg = newLastKnownGood(config.LastKnownGood)
+	}
+
+	metrics := metricsOrNoop(config.Metrics)
+
+	var breaker *circuitBreaker
+	if config.CircuitBreaker.Enabled {
+		breaker = newCircuitBreaker(config.CircuitBreaker, logger, metrics)
+	}
+
+	client, err := NewHTTPClient(config)
+	if err != nil {
+		logger.Error("Failed to create HTTP client: %v", err)
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	local, localErr := newLocalForMode(config)
+	if localErr != nil {
+		logger.Error("Failed to load local policy: %v", localErr)
+	}
+
+	var flights *flightGroup
+	if !config.DisableCoalescing {
+		flights = &flightGroup{logger: logger}
+	}
+
 	return &AccessClient{
-		config: config,
-		client: &http.Client{
-			Timeout: 5 * time.Second,
-		},
-		logger: logger,
+		config:     config,
+		client:     client,
+		logger:     logger,
+		metrics:    metrics,
+		tracer:     newTracer(config),
+		propagator: newPropagator(config),
+		cache:      cache,
+		lkg:        lkg,
+		flights:    flights,
+		retry:      newRetryPolicy(config.Retry),
+		breaker:    breaker,
+		local:      local,
+		localErr:   localErr,
+		initErr:    err,
 	}
 }
 
-// CheckAccess проверяет права доступа для указанного действия
+// CheckAccess проверяет права доступа для указанного действия.
+// При ошибке возвращает решение политики AllowOnFailure вместе с ошибкой,
+// поэтому true с ошибкой означает пропуск из-за сбоя. Чтобы отличать запрет
+// от пропуска из-за сбоя, используйте Authorize
 func (ac *AccessClient) CheckAccess(action, entitlements string) (bool, error) {
+	return ac.CheckAccessContext(context.Background(), action, entitlements)
+}
+
+// CheckAccessContext проверяет права доступа для указанного действия с учетом контекста.
+// Отмена контекста или истечение его дедлайна прерывает запрос к сервису locator-ars
+func (ac *AccessClient) CheckAccessContext(ctx context.Context, action, entitlements string) (bool, error) {
+	accessResponse, err := ac.CheckAccessDetailed(ctx, action, entitlements)
+	if err != nil {
+		return ac.failureDecision(ctx), err
+	}
+	return accessResponse.Allowed, nil
+}
+
+// Authorize проверяет права доступа и применяет политику AllowOnFailure, сохраняя
+// исходное решение сервиса: Outcome отличает запрет (OutcomeDenied) от пропуска
+// из-за сбоя (OutcomeFailOpen), а Err содержит ошибку, сопоставимую через errors.Is
+// с ErrUnavailable, ErrTimeout, ErrBadResponse и ErrMissingEntitlements
+func (ac *AccessClient) Authorize(ctx context.Context, action, entitlements string) AuthResult {
+	if entitlements == "" {
+		return AuthResult{Outcome: OutcomeUnauthorized, Err: ErrMissingEntitlements}
+	}
+
+	decision, err := ac.CheckAccessDetailed(ctx, action, entitlements)
+	if err != nil {
+		outcome := failureOutcome(ctx, ac.config.AllowOnFailure)
+		if outcome == OutcomeFailOpen {
+			ac.logger.Info("Access allowed on failure due to configuration")
+		}
+		return AuthResult{Outcome: outcome, Err: err}
+	}
+	if !decision.Allowed {
+		return AuthResult{Outcome: OutcomeDenied, Decision: decision}
+	}
+	return AuthResult{Outcome: OutcomeAllowed, Decision: decision}
+}
+
+// CheckAccessDetailed проверяет права доступа и возвращает полный ответ сервиса,
+// включая пользователя, сущность и сообщение. Политика AllowOnFailure здесь
+// не применяется: при ошибке возвращается nil и ошибка
+func (ac *AccessClient) CheckAccessDetailed(ctx context.Context, action, entitlements string) (decision *AccessResponse, err error) {
+	ctx, span := ac.tracer.Start(ctx, "locatorars.CheckAccess",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(AttrAction.String(action)))
+	defer func() { endCheckSpan(span, decision, err) }()
+
+	if decision, ok, err := ac.checkLocal(action, entitlements); ok {
+		return decision, err
+	}
+
+	key := cacheKey(action, entitlements)
+	if ac.cache != nil {
+		cached, ok := ac.cache.get(key)
+		ac.metrics.ObserveCacheLookup(ok)
+		span.SetAttributes(AttrCacheHit.Bool(ok))
+		if ok {
+			ac.logger.Debug("Access decision served from cache: Action=%s, Allowed=%v", action, cached.Allowed)
+			// Копируем карту пользователя, чтобы изменения в обработчике не затронули кэш
+			cached.User = maps.Clone(cached.User)
+			return &cached, nil
+		}
+	}
+
+	if decision, ok := ac.lastKnownGoodDuringOutage(key, action, entitlements); ok {
+		span.SetAttributes(AttrLastKnownGood.Bool(true))
+		return decision, nil
+	}
+
+	accessResponse, err := ac.fetchDecision(ctx, action, entitlements)
+	if err != nil {
+		if decision, ok := ac.lastKnownGoodOnError(ctx, key, action, entitlements, err); ok {
+			span.SetAttributes(AttrLastKnownGood.Bool(true))
+			return decision, nil
+		}
+		// Ошибки никогда не кэшируются
+		return nil, err
+	}
+
+	ac.storeDecision(key, *accessResponse)
+
+	// Проверяем значение поля allowed
+	if accessResponse.Allowed {
+		ac.logger.Debug("Access check successful, access granted. Response: %+v", *accessResponse)
+	} else {
+		ac.logger.Debug("Access check successful, but access denied. Response: %+v", *accessResponse)
+	}
+	return accessResponse, nil
+}
+
+// checkLocal принимает решение по локальной политике в режимах ModeLocal и ModeLocalFirst.
+// Возвращает false, если решение должен принять сервис locator-ars
+func (ac *AccessClient) checkLocal(action, entitlements string) (*AccessResponse, bool, error) {
+	switch ac.config.Mode {
+	case "", ModeRemote:
+		return nil, false, nil
+	case ModeLocal, ModeLocalFirst:
+	default:
+		return nil, true, fmt.Errorf("access client is misconfigured: %w", ac.localErr)
+	}
+
+	if ac.local == nil {
+		if ac.config.Mode == ModeLocalFirst {
+			// Политику загрузить не удалось, все решения принимает сервис
+			return nil, false, nil
+		}
+		return nil, true, fmt.Errorf("access client is misconfigured: %w", ac.localErr)
+	}
+
+	if decision, ok := ac.local.decide(action, entitlements); ok {
+		ac.logger.Debug("Access decision made by local policy: Action=%s, Allowed=%v", action, decision.Allowed)
+		return decision, true, nil
+	}
+	if ac.config.Mode == ModeLocal {
+		decision, err := ac.local.CheckAccessDetailed(context.Background(), action, entitlements)
+		ac.logger.Debug("Action %s is not defined in local policy, access denied", action)
+		return decision, true, err
+	}
+
+	ac.logger.Debug("Action %s is not defined in local policy, checking with access service", action)
+	return nil, false, nil
+}
+
+// CircuitState возвращает текущее состояние автоматического выключателя.
+// Если выключатель не настроен, всегда возвращает CircuitClosed
+func (ac *AccessClient) CircuitState() CircuitState {
+	if ac.breaker == nil {
+		return CircuitClosed
+	}
+	return ac.breaker.currentState()
+}
+
+// fetchDecision получает решение от сервиса. Одновременные проверки одного действия
+// с одинаковыми Entitlements выполняются одним запросом
+func (ac *AccessClient) fetchDecision(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+	if ac.flights == nil {
+		return ac.requestDecision(ctx, action, entitlements)
+	}
+
+	decision, shared, err := ac.flights.do(ctx, cacheKey(action, entitlements), "action: "+action, func(ctx context.Context) (*AccessResponse, error) {
+		return ac.requestDecision(ctx, action, entitlements)
+	})
+	trace.SpanFromContext(ctx).SetAttributes(AttrCoalesced.Bool(shared))
+	return decision, err
+}
+
+// requestDecision получает решение от сервиса с учетом автоматического выключателя и повторов
+func (ac *AccessClient) requestDecision(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+	var accessResponse *AccessResponse
+	err := ac.callService(ctx, "action: "+action, func() error {
+		var err error
+		accessResponse, err = ac.requestAccess(ctx, action, entitlements)
+		return err
+	})
+	return accessResponse, err
+}
+
+// callService выполняет обращение к сервису через автоматический выключатель и политику повторов
+func (ac *AccessClient) callService(ctx context.Context, target string, call func() error) error {
+	if ac.breaker == nil {
+		return ac.withRetry(ctx, target, call)
+	}
+
+	if err := ac.breaker.allow(); err != nil {
+		ac.logger.Debug("Circuit breaker is open, skipping access check for %s", target)
+		return err
+	}
+
+	err := ac.withRetry(ctx, target, call)
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+		ac.breaker.success()
+	case ctx.Err() != nil:
+		ac.breaker.release()
+	case isServiceFailure(err):
+		ac.breaker.failure()
+	case errors.Is(err, errBatchUnsupported), errors.As(err, &statusErr):
+		// Сервис ответил: ответ 4xx вызван запросом, а не отказом сервиса
+		ac.breaker.success()
+	default:
+		// Некорректный ответ или ошибка конфигурации не говорят о доступности сервиса
+		ac.breaker.release()
+	}
+	return err
+}
+
+// failureDecision возвращает решение по политике AllowOnFailure.
+// Отмена запроса вызывающей стороной не является отказом сервиса,
+// поэтому в этом случае доступ всегда запрещается
+func (ac *AccessClient) failureDecision(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if ac.config.AllowOnFailure {
+		ac.logger.Info("Access allowed on failure due to configuration")
+		return true
+	}
+	return false
+}
+
+// requestAccess выполняет запрос к сервису locator-ars и возвращает разобранный ответ
+func (ac *AccessClient) requestAccess(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+	if ac.initErr != nil {
+		return nil, fmt.Errorf("access client is misconfigured: %w", ac.initErr)
+	}
+
 	startTime := time.Now()
 
 	// Формируем URL запроса
@@ -51,53 +345,42 @@ func (ac *AccessClient) CheckAccess(action, entitlements string) (bool, error) {
 	ac.logger.Debug("Making access check request: URL=%s, Action=%s", url, action)
 
 	// Создаем HTTP запрос
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		ac.logger.Error("Failed to create request: %v", err)
-		if ac.config.AllowOnFailure {
-			return true, err
-		}
-		return false, err
+		return nil, err
 	}
 
-	// Добавляем необходимые заголовки
+	// Добавляем необходимые заголовки и контекст трассировки
 	req.Header.Set("X-Authentik-Entitlements", entitlements)
+	ac.injectTraceContext(ctx, req)
 	ac.logger.Debug("Entitlements present=%v", len(entitlements) > 0)
 
 	// Выполняем запрос
 	ac.logger.Debug("Sending access check request...")
 	resp, err := ac.client.Do(req)
 	if err != nil {
+		ac.metrics.ObserveRequest(EndpointCheck, 0, time.Since(startTime))
 		ac.logger.Error("HTTP request failed: %v", err)
-		if ac.config.AllowOnFailure {
-			ac.logger.Info("Access allowed on failure due to configuration")
-			return true, err
-		}
-		return false, err
+		return nil, &transportError{err: err}
 	}
 	defer resp.Body.Close()
+	ac.metrics.ObserveRequest(EndpointCheck, resp.StatusCode, time.Since(startTime))
+	trace.SpanFromContext(ctx).SetAttributes(AttrStatusCode.Int(resp.StatusCode))
 	elapsedMs := time.Since(startTime).Milliseconds()
 	ac.logger.Debug("Access check response received in %d ms: StatusCode=%d", elapsedMs, resp.StatusCode)
 
 	// Проверяем статус ответа
 	if resp.StatusCode != http.StatusOK {
 		ac.logger.Error("Access service returned non-200 status: %d", resp.StatusCode)
-		if ac.config.AllowOnFailure {
-			ac.logger.Info("Access allowed on failure due to configuration")
-			return true, fmt.Errorf("access service returned non-200 status: %d", resp.StatusCode)
-		}
-		return false, fmt.Errorf("access service returned non-200 status: %d", resp.StatusCode)
+		return nil, newStatusError(resp)
 	}
 
 	// Читаем тело ответа
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		ac.logger.Error("Failed to read response body: %v", err)
-		if ac.config.AllowOnFailure {
-			ac.logger.Info("Access allowed on failure due to configuration")
-			return true, err
-		}
-		return false, err
+		return nil, &transportError{err: err}
 	}
 
 	ac.logger.Debug("Response body: %s", string(body))
@@ -106,19 +389,9 @@ func (ac *AccessClient) CheckAccess(action, entitlements string) (bool, error) {
 	var accessResponse AccessResponse
 	if err := json.Unmarshal(body, &accessResponse); err != nil {
 		ac.logger.Error("Failed to parse JSON response: %v", err)
-		if ac.config.AllowOnFailure {
-			ac.logger.Info("Access allowed on failure due to configuration")
-			return true, err
-		}
-		return false, err
+		return nil, fmt.Errorf("%w: %w", ErrBadResponse, err)
 	}
+	accessResponse.confirmedAt = time.Now()
 
-	// Проверяем значение поля allowed
-	if accessResponse.Allowed {
-		ac.logger.Debug("Access check successful, access granted. Response: %+v", accessResponse)
-		return true, nil
-	} else {
-		ac.logger.Debug("Access check successful, but access denied. Response: %+v", accessResponse)
-		return false, nil
-	}
+	return &accessResponse, nil
 }
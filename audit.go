@@ -0,0 +1,299 @@
+package locatorars
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RequestIDHeader заголовок с идентификатором входящего запроса
+const RequestIDHeader = "X-Request-Id"
+
+// DecisionEvent запись журнала аудита об одном решении о доступе
+type DecisionEvent struct {
+	// Время решения
+	Time time.Time `json:"time"`
+
+	// Действие или выражение над действиями
+	Action string `json:"action"`
+
+	// Пользователь из ответа сервиса (AccessResponse.User), если он известен
+	User map[string]interface{} `json:"user,omitempty"`
+
+	// Данные входящего запроса
+	ClientIP  string `json:"client_ip,omitempty"`
+	Route     string `json:"route,omitempty"`
+	Method    string `json:"method,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// Итог проверки: allowed, denied, unauthorized, error, fail-open, timeout, cancelled, stale
+	Decision string `json:"decision"`
+
+	// Пропущен ли запрос
+	Allowed bool `json:"allowed"`
+
+	// Причина решения: сообщение сервиса, запрещенные действия или ошибка
+	Reason string `json:"reason,omitempty"`
+
+	// Длительность проверки
+	Latency time.Duration `json:"-"`
+}
+
+// MarshalJSON добавляет длительность проверки в миллисекундах
+func (e DecisionEvent) MarshalJSON() ([]byte, error) {
+	type event DecisionEvent
+	return json.Marshal(struct {
+		event
+		LatencyMs float64 `json:"latency_ms"`
+	}{event(e), float64(e.Latency) / float64(time.Millisecond)})
+}
+
+// AuditSink принимает записи журнала аудита.
+// Record вызывается конкурентно на пути обработки запроса, поэтому
+// медленные приемники следует оборачивать в NewAsyncSink
+type AuditSink interface {
+	Record(event DecisionEvent) error
+}
+
+// RequestInfo данные входящего запроса для журнала аудита
+type RequestInfo struct {
+	ClientIP  string
+	Route     string
+	Method    string
+	RequestID string
+}
+
+// requestInfoKey тип ключа контекста для RequestInfo
+type requestInfoKey struct{}
+
+// ContextWithRequestInfo сохраняет данные входящего запроса в контексте.
+// Адаптеры вызывают его перед Authorize, чтобы запись аудита содержала маршрут и адрес клиента
+func ContextWithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext возвращает данные входящего запроса, сохраненные ContextWithRequestInfo
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
+
+// HTTPRequestInfo собирает данные входящего net/http запроса. Маршрутом считается
+// шаблон http.ServeMux, если он известен, иначе путь запроса
+func HTTPRequestInfo(r *http.Request) RequestInfo {
+	route := r.Pattern
+	if route == "" {
+		route = r.URL.Path
+	}
+
+	clientIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		clientIP = host
+	}
+
+	return RequestInfo{
+		ClientIP:  clientIP,
+		Route:     route,
+		Method:    r.Method,
+		RequestID: r.Header.Get(RequestIDHeader),
+	}
+}
+
+// audit записывает решение в журнал аудита, если он настроен
+func (m *Middleware) audit(ctx context.Context, action string, startTime time.Time, result AuthResult) {
+	if m.auditSink == nil {
+		return
+	}
+
+	info, _ := RequestInfoFromContext(ctx)
+	event := DecisionEvent{
+		Time:      startTime,
+		Action:    action,
+		ClientIP:  info.ClientIP,
+		Route:     info.Route,
+		Method:    info.Method,
+		RequestID: info.RequestID,
+		Decision:  result.Outcome.String(),
+		Allowed:   result.Allowed(),
+		Reason:    auditReason(result),
+		Latency:   time.Since(startTime),
+	}
+	if result.Decision != nil {
+		// Копия, так как асинхронный приемник пишет событие после возврата из обработчика
+		event.User = maps.Clone(result.Decision.User)
+	}
+
+	if err := m.auditSink.Record(event); err != nil {
+		m.logger.Error("Failed to record audit event for %s: %v", action, err)
+	}
+}
+
+// auditReason формирует причину решения для журнала аудита
+func auditReason(result AuthResult) string {
+	switch {
+	case result.Err != nil:
+		return result.Err.Error()
+	case len(result.Missing) > 0:
+		return "missing: " + strings.Join(result.Missing, ", ")
+	case result.Outcome == OutcomeUnauthorized:
+		return "missing entitlements"
+	case result.Decision != nil:
+		return result.Decision.Message
+	default:
+		return ""
+	}
+}
+
+// JSONLinesSink записывает события аудита в формате JSON Lines, по одному объекту в строке
+type JSONLinesSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+// NewJSONLinesSink создает приемник, пишущий в w
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{encoder: json.NewEncoder(w)}
+}
+
+// OpenJSONLinesFile открывает файл для дозаписи событий аудита, создавая его при необходимости
+func OpenJSONLinesFile(path string) (*JSONLinesSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	sink := NewJSONLinesSink(file)
+	sink.closer = file
+	return sink, nil
+}
+
+// Record записывает событие
+func (s *JSONLinesSink) Record(event DecisionEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encoder.Encode(event)
+}
+
+// Close закрывает файл, открытый OpenJSONLinesFile
+func (s *JSONLinesSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// OverflowPolicy поведение асинхронного приемника при заполненном буфере
+type OverflowPolicy int
+
+const (
+	// OverflowDrop - отбросить событие, не задерживая запрос
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock - ждать освобождения места в буфере
+	OverflowBlock
+)
+
+const defaultAuditBufferSize = 1024
+
+// ErrSinkClosed событие записывается в закрытый приемник
+var ErrSinkClosed = errors.New("audit sink is closed")
+
+// AsyncSinkConfig параметры асинхронного приемника
+type AsyncSinkConfig struct {
+	// Размер буфера событий
+	// По умолчанию: 1024
+	BufferSize int
+
+	// Поведение при заполненном буфере
+	// По умолчанию: OverflowDrop
+	Overflow OverflowPolicy
+
+	// Обработчик ошибок обернутого приемника (если nil, ошибки игнорируются)
+	OnError func(err error)
+}
+
+// AsyncSink передает события обернутому приемнику в фоновой горутине через буферизованный канал
+type AsyncSink struct {
+	next    AuditSink
+	config  AsyncSinkConfig
+	events  chan DecisionEvent
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAsyncSink создает асинхронный приемник поверх next. Для сброса буфера вызовите Close
+func NewAsyncSink(next AuditSink, config AsyncSinkConfig) *AsyncSink {
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaultAuditBufferSize
+	}
+
+	s := &AsyncSink{
+		next:   next,
+		config: config,
+		events: make(chan DecisionEvent, config.BufferSize),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Record ставит событие в очередь. При заполненном буфере событие
+// отбрасывается или запрос ожидает в зависимости от Overflow
+func (s *AsyncSink) Record(event DecisionEvent) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+
+	if s.config.Overflow == OverflowBlock {
+		s.events <- event
+		return nil
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Add(1)
+	}
+	return nil
+}
+
+// Dropped возвращает количество событий, отброшенных из-за переполнения буфера
+func (s *AsyncSink) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close прекращает прием событий и ждет записи оставшихся в буфере
+func (s *AsyncSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+	return nil
+}
+
+// run передает события обернутому приемнику
+func (s *AsyncSink) run() {
+	defer close(s.done)
+	for event := range s.events {
+		if err := s.next.Record(event); err != nil && s.config.OnError != nil {
+			s.config.OnError(err)
+		}
+	}
+}
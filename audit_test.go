@@ -0,0 +1,279 @@
+package locatorars_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+// gatedSink передает события в канал started и ждет закрытия release
+type gatedSink struct {
+	started chan string
+	release chan struct{}
+
+	mu     sync.Mutex
+	events []string
+}
+
+func newGatedSink() *gatedSink {
+	return &gatedSink{started: make(chan string, 16), release: make(chan struct{})}
+}
+
+func (s *gatedSink) Record(event locatorars.DecisionEvent) error {
+	s.started <- event.Action
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event.Action)
+	return nil
+}
+
+func (s *gatedSink) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.events...)
+}
+
+// fillAsyncSink записывает событие, которое обрабатывает фоновая горутина,
+// и событие, которое занимает буфер размером 1
+func fillAsyncSink(t *testing.T, sink *locatorars.AsyncSink, next *gatedSink) {
+	t.Helper()
+	if err := sink.Record(locatorars.DecisionEvent{Action: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	<-next.started
+	if err := sink.Record(locatorars.DecisionEvent{Action: "buffered"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAsyncSinkDropsOnOverflow(t *testing.T) {
+	next := newGatedSink()
+	sink := locatorars.NewAsyncSink(next, locatorars.AsyncSinkConfig{BufferSize: 1})
+	fillAsyncSink(t, sink, next)
+
+	startTime := time.Now()
+	if err := sink.Record(locatorars.DecisionEvent{Action: "dropped"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if elapsed := time.Since(startTime); elapsed > 100*time.Millisecond {
+		t.Errorf("Record blocked for %v with OverflowDrop", elapsed)
+	}
+	if sink.Dropped() != 1 {
+		t.Errorf("dropped %d events, want 1", sink.Dropped())
+	}
+
+	close(next.release)
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := next.recorded(); len(got) != 2 || got[0] != "first" || got[1] != "buffered" {
+		t.Errorf("recorded %v, want [first buffered]", got)
+	}
+}
+
+func TestAsyncSinkBlocksOnOverflow(t *testing.T) {
+	next := newGatedSink()
+	sink := locatorars.NewAsyncSink(next, locatorars.AsyncSinkConfig{BufferSize: 1, Overflow: locatorars.OverflowBlock})
+	fillAsyncSink(t, sink, next)
+
+	recorded := make(chan error, 1)
+	go func() { recorded <- sink.Record(locatorars.DecisionEvent{Action: "blocked"}) }()
+
+	select {
+	case <-recorded:
+		t.Fatal("Record returned while the buffer was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(next.release)
+	if err := <-recorded; err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := next.recorded(); len(got) != 3 || sink.Dropped() != 0 {
+		t.Errorf("recorded %v, dropped %d, want 3 events and none dropped", got, sink.Dropped())
+	}
+}
+
+func TestAsyncSinkClose(t *testing.T) {
+	next := newGatedSink()
+	close(next.release)
+	var errs []error
+	failing := locatorars.AuditSink(sinkFunc(func(event locatorars.DecisionEvent) error {
+		if err := next.Record(event); err != nil {
+			return err
+		}
+		if event.Action == "failing" {
+			return errors.New("write failed")
+		}
+		return nil
+	}))
+	sink := locatorars.NewAsyncSink(failing, locatorars.AsyncSinkConfig{
+		BufferSize: 16,
+		OnError:    func(err error) { errs = append(errs, err) },
+	})
+
+	for _, action := range []string{"a", "b", "failing", "c"} {
+		if err := sink.Record(locatorars.DecisionEvent{Action: action}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	// Close дожидается записи всех событий из буфера
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := next.recorded(); len(got) != 4 {
+		t.Errorf("recorded %v after Close, want 4 events", got)
+	}
+	if len(errs) != 1 {
+		t.Errorf("OnError called %d times, want 1", len(errs))
+	}
+
+	if err := sink.Record(locatorars.DecisionEvent{Action: "late"}); !errors.Is(err, locatorars.ErrSinkClosed) {
+		t.Errorf("Record after Close: %v, want ErrSinkClosed", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+// sinkFunc позволяет использовать функцию как AuditSink
+type sinkFunc func(event locatorars.DecisionEvent) error
+
+func (f sinkFunc) Record(event locatorars.DecisionEvent) error {
+	return f(event)
+}
+
+func TestJSONLinesSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := locatorars.NewJSONLinesSink(&buf)
+
+	events := []locatorars.DecisionEvent{
+		{
+			Time:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Action:   "reports.view",
+			User:     map[string]interface{}{"name": "alice"},
+			ClientIP: "10.0.0.1",
+			Route:    "/reports",
+			Method:   "GET",
+			Decision: "allowed",
+			Allowed:  true,
+			Latency:  1500 * time.Microsecond,
+		},
+		{Time: time.Date(2026, 1, 2, 3, 4, 6, 0, time.UTC), Action: "reports.edit", Decision: "denied", Reason: "missing: reports.edit"},
+	}
+	for _, event := range events {
+		if err := sink.Record(event); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d lines, want 2", len(lines))
+	}
+
+	first := lines[0]
+	if first["time"] != "2026-01-02T03:04:05Z" || first["action"] != "reports.view" || first["decision"] != "allowed" ||
+		first["allowed"] != true || first["client_ip"] != "10.0.0.1" || first["route"] != "/reports" || first["method"] != "GET" {
+		t.Errorf("first line %v", first)
+	}
+	if first["latency_ms"] != 1.5 {
+		t.Errorf("latency_ms %v, want 1.5", first["latency_ms"])
+	}
+	if user, _ := first["user"].(map[string]interface{}); user["name"] != "alice" {
+		t.Errorf("user %v, want alice", first["user"])
+	}
+	if _, ok := first["Latency"]; ok {
+		t.Error("latency is written as a duration field")
+	}
+
+	second := lines[1]
+	if second["reason"] != "missing: reports.edit" || second["allowed"] != false {
+		t.Errorf("second line %v", second)
+	}
+	for _, key := range []string{"user", "client_ip", "route", "method", "request_id"} {
+		if _, ok := second[key]; ok {
+			t.Errorf("empty %s is not omitted", key)
+		}
+	}
+}
+
+func TestOpenJSONLinesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	// Файл дописывается при повторном открытии
+	for _, action := range []string{"first", "second"} {
+		sink, err := locatorars.OpenJSONLinesFile(path)
+		if err != nil {
+			t.Fatalf("OpenJSONLinesFile: %v", err)
+		}
+		if err := sink.Record(locatorars.DecisionEvent{Action: action}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 2 {
+		t.Errorf("file has %d lines, want 2:\n%s", lines, data)
+	}
+}
+
+func TestAuditEventUser(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	s.AllowUser("profile.view", map[string]interface{}{"name": "alice"})
+
+	audit := &auditEvents{}
+	config := s.Config()
+	config.AuditSink = audit
+	m := locatorars.NewMiddleware(config)
+
+	result := m.Authorize(context.Background(), "profile.view", "profile")
+	if !result.Allowed() {
+		t.Fatalf("outcome %v, want allowed", result.Outcome)
+	}
+
+	// Обработчик может изменить ответ сервиса после записи события асинхронным приемником
+	result.Decision.User["name"] = "mallory"
+	if got := audit.events[0].User["name"]; got != "alice" {
+		t.Errorf("audit user name %v, want alice", got)
+	}
+}
+
+// auditEvents запоминает события журнала аудита по порядку
+type auditEvents struct {
+	events []locatorars.DecisionEvent
+}
+
+func (a *auditEvents) Record(event locatorars.DecisionEvent) error {
+	a.events = append(a.events, event)
+	return nil
+}
@@ -0,0 +1,212 @@
+package locatorars
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultBatchConcurrency = 4
+
+// errBatchUnsupported сервис не предоставляет пакетный эндпоинт
+var errBatchUnsupported = errors.New("access service does not support batch checks")
+
+// batchRequest тело запроса к пакетному эндпоинту
+type batchRequest struct {
+	Actions []string `json:"actions"`
+}
+
+// batchResponse ответ пакетного эндпоинта
+type batchResponse struct {
+	Results []AccessResponse `json:"results"`
+}
+
+// CheckActions проверяет права доступа сразу для нескольких действий.
+// Если задан BatchURL и сервис его поддерживает, выполняется один пакетный запрос,
+// иначе действия проверяются параллельно с ограничением BatchConcurrency.
+// Для действий, проверить которые не удалось, значение определяется политикой
+// AllowOnFailure, а ошибки объединяются в возвращаемой ошибке
+func (ac *AccessClient) CheckActions(ctx context.Context, entitlements string, actions []string) (results map[string]bool, err error) {
+	ctx, span := ac.tracer.Start(ctx, "locatorars.CheckActions",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(AttrAction.StringSlice(actions)))
+	defer func() { endCheckSpan(span, nil, err) }()
+
+	results = make(map[string]bool, len(actions))
+	pending := make([]string, 0, len(actions))
+	for _, action := range actions {
+		if _, ok := results[action]; ok {
+			continue
+		}
+		results[action] = false
+
+		if decision, ok, err := ac.checkLocal(action, entitlements); ok {
+			if err != nil {
+				// Ошибка конфигурации относится ко всем действиям
+				decision := ac.failureDecision(ctx)
+				for _, action := range actions {
+					results[action] = decision
+				}
+				return results, err
+			}
+			results[action] = decision.Allowed
+			continue
+		}
+
+		if ac.cache != nil {
+			cached, ok := ac.cache.get(cacheKey(action, entitlements))
+			ac.metrics.ObserveCacheLookup(ok)
+			if ok {
+				results[action] = cached.Allowed
+				continue
+			}
+		}
+		pending = append(pending, action)
+	}
+
+	if len(pending) == 0 {
+		ac.logger.Debug("Batch check for %d actions served from cache", len(results))
+		return results, nil
+	}
+
+	if ac.config.BatchURL != "" && !ac.batchUnsupported.Load() {
+		missing, err := ac.checkBatch(ctx, entitlements, pending, results)
+		if err == nil {
+			pending = missing
+		} else if errors.Is(err, errBatchUnsupported) {
+			ac.batchUnsupported.Store(true)
+			ac.logger.Info("Batch endpoint is not supported by access service, falling back to single checks")
+		} else {
+			return results, ac.batchFailure(ctx, entitlements, pending, results, err)
+		}
+	}
+
+	if len(pending) == 0 {
+		return results, nil
+	}
+	return results, checkParallel(ctx, ac.config.BatchConcurrency, pending, results, func(ctx context.Context, action string) (bool, error) {
+		return ac.CheckAccessContext(ctx, action, entitlements)
+	})
+}
+
+// batchFailure заполняет results для действий, которые не удалось проверить пакетным запросом:
+// последним подтвержденным решением, если оно есть, иначе по политике AllowOnFailure.
+// Возвращает ошибку для каждого действия, для которого решения нет
+func (ac *AccessClient) batchFailure(ctx context.Context, entitlements string, pending []string, results map[string]bool, err error) error {
+	decision := ac.failureDecision(ctx)
+	var errs []error
+	for _, action := range pending {
+		if known, ok := ac.lastKnownGoodOnError(ctx, cacheKey(action, entitlements), action, entitlements, err); ok {
+			results[action] = known.Allowed
+			continue
+		}
+		results[action] = decision
+		errs = append(errs, &actionError{action: action, err: err})
+	}
+	return errors.Join(errs...)
+}
+
+// checkBatch выполняет пакетный запрос и заполняет results.
+// Возвращает действия, отсутствующие в ответе сервиса
+func (ac *AccessClient) checkBatch(ctx context.Context, entitlements string, actions []string, results map[string]bool) ([]string, error) {
+	var responses []AccessResponse
+	err := ac.callService(ctx, fmt.Sprintf("batch of %d actions", len(actions)), func() error {
+		var err error
+		responses, err = ac.requestBatch(ctx, entitlements, actions)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	received := make(map[string]AccessResponse, len(responses))
+	for _, response := range responses {
+		received[response.Action] = response
+	}
+
+	var missing []string
+	for _, action := range actions {
+		response, ok := received[action]
+		if !ok {
+			missing = append(missing, action)
+			continue
+		}
+		results[action] = response.Allowed
+		ac.storeDecision(cacheKey(action, entitlements), response)
+	}
+
+	if len(missing) > 0 {
+		ac.logger.Debug("Batch response is missing %d actions, checking them individually", len(missing))
+	}
+	return missing, nil
+}
+
+// requestBatch выполняет запрос к пакетному эндпоинту сервиса locator-ars
+func (ac *AccessClient) requestBatch(ctx context.Context, entitlements string, actions []string) ([]AccessResponse, error) {
+	if ac.initErr != nil {
+		return nil, fmt.Errorf("access client is misconfigured: %w", ac.initErr)
+	}
+
+	startTime := time.Now()
+
+	payload, err := json.Marshal(batchRequest{Actions: actions})
+	if err != nil {
+		return nil, err
+	}
+
+	ac.logger.Debug("Making batch access check request: URL=%s, Actions=%v", ac.config.BatchURL, actions)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ac.config.BatchURL, bytes.NewReader(payload))
+	if err != nil {
+		ac.logger.Error("Failed to create batch request: %v", err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Authentik-Entitlements", entitlements)
+	ac.injectTraceContext(ctx, req)
+
+	resp, err := ac.client.Do(req)
+	if err != nil {
+		ac.metrics.ObserveRequest(EndpointBatch, 0, time.Since(startTime))
+		ac.logger.Error("Batch HTTP request failed: %v", err)
+		return nil, &transportError{err: err}
+	}
+	defer resp.Body.Close()
+	ac.metrics.ObserveRequest(EndpointBatch, resp.StatusCode, time.Since(startTime))
+	trace.SpanFromContext(ctx).SetAttributes(AttrStatusCode.Int(resp.StatusCode))
+	elapsedMs := time.Since(startTime).Milliseconds()
+	ac.logger.Debug("Batch access check response received in %d ms: StatusCode=%d", elapsedMs, resp.StatusCode)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, errBatchUnsupported
+	default:
+		ac.logger.Error("Access service returned non-200 status for batch: %d", resp.StatusCode)
+		return nil, newStatusError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		ac.logger.Error("Failed to read batch response body: %v", err)
+		return nil, &transportError{err: err}
+	}
+
+	var parsed batchResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		ac.logger.Error("Failed to parse batch JSON response: %v", err)
+		return nil, fmt.Errorf("%w: %w", ErrBadResponse, err)
+	}
+	confirmedAt := time.Now()
+	for i := range parsed.Results {
+		parsed.Results[i].confirmedAt = confirmedAt
+	}
+
+	return parsed.Results, nil
+}
@@ -0,0 +1,154 @@
+package locatorars_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+// auditRecorder запоминает события журнала аудита по действиям
+type auditRecorder struct {
+	mu     sync.Mutex
+	events map[string]locatorars.DecisionEvent
+}
+
+func (r *auditRecorder) Record(event locatorars.DecisionEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.events == nil {
+		r.events = make(map[string]locatorars.DecisionEvent)
+	}
+	r.events[event.Action] = event
+	return nil
+}
+
+func (r *auditRecorder) decision(action string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events[action].Decision
+}
+
+// batchCalls возвращает количество действий, проверенных пакетными и одиночными запросами
+func batchCalls(s *locatorarstest.Server) (batch, single int) {
+	for _, request := range s.Requests() {
+		if request.Batch {
+			batch++
+		} else {
+			single++
+		}
+	}
+	return batch, single
+}
+
+func TestCheckActions(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	s.Allow("reports.view", "reports")
+	s.Deny("reports.edit")
+
+	tests := []struct {
+		name       string
+		batchURL   string
+		wantBatch  int // действий в пакетном запросе
+		wantSingle int // одиночных запросов
+	}{
+		{name: "batch endpoint", batchURL: s.URL + locatorarstest.BatchPath, wantBatch: 2},
+		{name: "batch endpoint not supported", batchURL: s.URL + "/missing", wantSingle: 2},
+		{name: "single checks", wantSingle: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.ResetRequests()
+			config := s.Config()
+			config.BatchURL = tt.batchURL
+			m := locatorars.NewMiddleware(config)
+
+			// Повторяющиеся действия проверяются один раз
+			actions := []string{"reports.view", "reports.edit", "reports.view"}
+			for i := 0; i < 2; i++ {
+				results, err := m.CheckActions(context.Background(), "reports", actions)
+				if err != nil {
+					t.Fatalf("CheckActions: %v", err)
+				}
+				if len(results) != 2 || !results["reports.view"] || results["reports.edit"] {
+					t.Fatalf("results %v, want reports.view allowed and reports.edit denied", results)
+				}
+			}
+
+			// Без кэша каждый вызов снова обращается к сервису, а после отказа
+			// пакетного эндпоинта он больше не используется
+			batch, single := batchCalls(s)
+			if batch != 2*tt.wantBatch || single != 2*tt.wantSingle {
+				t.Errorf("batch requests %d, single requests %d, want %d and %d", batch, single, 2*tt.wantBatch, 2*tt.wantSingle)
+			}
+		})
+	}
+}
+
+func TestCheckActionsFailure(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	s.Allow("reports.view", "reports")
+	s.Fail("reports.broken", http.StatusServiceUnavailable)
+
+	tests := []struct {
+		name           string
+		batch          bool
+		allowOnFailure bool
+		want           map[string]bool
+		wantOutcomes   map[string]string
+	}{
+		{
+			name:         "single checks fail closed",
+			want:         map[string]bool{"reports.view": true, "reports.broken": false},
+			wantOutcomes: map[string]string{"reports.view": "allowed", "reports.broken": "error"},
+		},
+		{
+			name:           "single checks fail open",
+			allowOnFailure: true,
+			want:           map[string]bool{"reports.view": true, "reports.broken": true},
+			wantOutcomes:   map[string]string{"reports.view": "allowed", "reports.broken": "fail-open"},
+		},
+		{
+			// Ошибка пакетного запроса относится ко всем действиям пакета
+			name:         "batch fails closed",
+			batch:        true,
+			want:         map[string]bool{"reports.view": false, "reports.broken": false},
+			wantOutcomes: map[string]string{"reports.view": "error", "reports.broken": "error"},
+		},
+		{
+			name:           "batch fails open",
+			batch:          true,
+			allowOnFailure: true,
+			want:           map[string]bool{"reports.view": true, "reports.broken": true},
+			wantOutcomes:   map[string]string{"reports.view": "fail-open", "reports.broken": "fail-open"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &auditRecorder{}
+			config := s.Config()
+			config.AllowOnFailure = tt.allowOnFailure
+			config.AuditSink = audit
+			if !tt.batch {
+				config.BatchURL = ""
+			}
+
+			results, err := locatorars.NewMiddleware(config).CheckActions(context.Background(), "reports", []string{"reports.view", "reports.broken"})
+			if !errors.Is(err, locatorars.ErrUnavailable) {
+				t.Fatalf("err %v, want ErrUnavailable", err)
+			}
+			for action, want := range tt.want {
+				if results[action] != want {
+					t.Errorf("%s: %v, want %v", action, results[action], want)
+				}
+				if got := audit.decision(action); got != tt.wantOutcomes[action] {
+					t.Errorf("%s: audit decision %q, want %q", action, got, tt.wantOutcomes[action])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package locatorars
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerFailureThreshold    = 5
+	defaultBreakerCoolDown            = 30 * time.Second
+	defaultBreakerHalfOpenMaxRequests = 1
+)
+
+// ErrCircuitOpen возвращается без обращения к locator-ars, пока автоматический выключатель разомкнут.
+// errors.Is(ErrCircuitOpen, ErrUnavailable) возвращает true
+var ErrCircuitOpen = fmt.Errorf("locator-ars circuit breaker is open: %w", ErrUnavailable)
+
+// CircuitState состояние автоматического выключателя
+type CircuitState int
+
+const (
+	// CircuitClosed - запросы проходят к сервису, ошибки подсчитываются
+	CircuitClosed CircuitState = iota
+	// CircuitOpen - запросы не выполняются, сразу применяется политика AllowOnFailure
+	CircuitOpen
+	// CircuitHalfOpen - после паузы пропускается ограниченное число пробных запросов
+	CircuitHalfOpen
+)
+
+// String возвращает название состояния
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig определяет параметры автоматического выключателя
+// для зависимости от сервиса locator-ars
+type CircuitBreakerConfig struct {
+	// Включает автоматический выключатель
+	Enabled bool
+
+	// Количество последовательных ошибок, после которого выключатель размыкается
+	// По умолчанию: 5
+	FailureThreshold int
+
+	// Время, в течение которого выключатель остается разомкнутым,
+	// прежде чем пропустить пробные запросы
+	// По умолчанию: 30 секунд
+	CoolDown time.Duration
+
+	// Количество пробных запросов в полуоткрытом состоянии. Столько же
+	// успешных ответов подряд требуется, чтобы замкнуть выключатель
+	// По умолчанию: 1
+	HalfOpenMaxRequests int
+}
+
+// circuitBreaker потокобезопасная реализация автоматического выключателя
+type circuitBreaker struct {
+	mu                  sync.Mutex
+	failureThreshold    int
+	coolDown            time.Duration
+	halfOpenMaxRequests int
+	logger              Logger
+	metrics             Metrics
+
+	state             CircuitState
+	failures          int
+	openedAt          time.Time
+	halfOpenInFlight  int
+	halfOpenSuccesses int
+}
+
+// newCircuitBreaker создает выключатель, подставляя значения по умолчанию
+func newCircuitBreaker(config CircuitBreakerConfig, logger Logger, metrics Metrics) *circuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaultBreakerFailureThreshold
+	}
+	if config.CoolDown <= 0 {
+		config.CoolDown = defaultBreakerCoolDown
+	}
+	if config.HalfOpenMaxRequests <= 0 {
+		config.HalfOpenMaxRequests = defaultBreakerHalfOpenMaxRequests
+	}
+
+	metrics.SetCircuitState(CircuitClosed)
+	return &circuitBreaker{
+		failureThreshold:    config.FailureThreshold,
+		coolDown:            config.CoolDown,
+		halfOpenMaxRequests: config.HalfOpenMaxRequests,
+		logger:              logger,
+		metrics:             metrics,
+		state:               CircuitClosed,
+	}
+}
+
+// currentState возвращает текущее состояние выключателя
+func (cb *circuitBreaker) currentState() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// allow проверяет, можно ли выполнить запрос к сервису.
+// Возвращает ErrCircuitOpen, если запрос должен завершиться сразу
+func (cb *circuitBreaker) allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if time.Since(cb.openedAt) < cb.coolDown {
+			return ErrCircuitOpen
+		}
+		cb.setState(CircuitHalfOpen)
+		cb.halfOpenInFlight = 1
+		return nil
+	case CircuitHalfOpen:
+		if cb.halfOpenInFlight >= cb.halfOpenMaxRequests {
+			return ErrCircuitOpen
+		}
+		cb.halfOpenInFlight++
+		return nil
+	default:
+		return nil
+	}
+}
+
+// success фиксирует успешный ответ сервиса
+func (cb *circuitBreaker) success() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		cb.halfOpenInFlight--
+		cb.halfOpenSuccesses++
+		if cb.halfOpenSuccesses >= cb.halfOpenMaxRequests {
+			cb.setState(CircuitClosed)
+		}
+	default:
+		cb.failures = 0
+	}
+}
+
+// failure фиксирует отказ сервиса
+func (cb *circuitBreaker) failure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		cb.halfOpenInFlight--
+		cb.setState(CircuitOpen)
+	case CircuitClosed:
+		cb.failures++
+		if cb.failures >= cb.failureThreshold {
+			cb.setState(CircuitOpen)
+		}
+	}
+}
+
+// release освобождает пробный слот без изменения состояния,
+// например, если запрос был отменен вызывающей стороной
+func (cb *circuitBreaker) release() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitHalfOpen && cb.halfOpenInFlight > 0 {
+		cb.halfOpenInFlight--
+	}
+}
+
+// setState переводит выключатель в новое состояние и логирует переход.
+// Вызывается под блокировкой
+func (cb *circuitBreaker) setState(state CircuitState) {
+	if cb.state == state {
+		return
+	}
+
+	previous := cb.state
+	cb.state = state
+	cb.failures = 0
+	cb.halfOpenInFlight = 0
+	cb.halfOpenSuccesses = 0
+	cb.metrics.SetCircuitState(state)
+
+	switch state {
+	case CircuitOpen:
+		cb.openedAt = time.Now()
+		cb.logger.Error("Circuit breaker state changed: %s -> %s, locator-ars calls suspended for %v", previous, state, cb.coolDown)
+	default:
+		cb.logger.Info("Circuit breaker state changed: %s -> %s", previous, state)
+	}
+}
@@ -0,0 +1,58 @@
+package locatorars_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantState locatorars.CircuitState
+	}{
+		{name: "bad request", status: http.StatusBadRequest, wantState: locatorars.CircuitClosed},
+		{name: "not found", status: http.StatusNotFound, wantState: locatorars.CircuitClosed},
+		{name: "service unavailable", status: http.StatusServiceUnavailable, wantState: locatorars.CircuitOpen},
+		{name: "internal error", status: http.StatusInternalServerError, wantState: locatorars.CircuitOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := locatorarstest.NewServer(t)
+			s.Fail("bad", tt.status)
+			s.Allow("good", "reports")
+
+			config := s.Config()
+			config.CircuitBreaker = locatorars.CircuitBreakerConfig{Enabled: true, FailureThreshold: 3}
+			client := locatorars.NewAccessClient(config)
+
+			for i := 0; i < 3; i++ {
+				if _, err := client.CheckAccessDetailed(context.Background(), "bad", "reports"); err == nil {
+					t.Fatal("check of failing action returned no error")
+				}
+			}
+			if state := client.CircuitState(); state != tt.wantState {
+				t.Fatalf("breaker state %v, want %v", state, tt.wantState)
+			}
+
+			_, err := client.CheckAccessDetailed(context.Background(), "good", "reports")
+			if tt.wantState == locatorars.CircuitOpen {
+				if !errors.Is(err, locatorars.ErrCircuitOpen) {
+					t.Fatalf("err %v, want ErrCircuitOpen", err)
+				}
+				if calls := s.Calls("good"); calls != 0 {
+					t.Errorf("open breaker sent %d requests", calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("check after client errors: %v", err)
+			}
+		})
+	}
+}
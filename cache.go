@@ -0,0 +1,148 @@
+package locatorars
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheAllowTTL   = 30 * time.Second
+	defaultCacheDenyTTL    = 5 * time.Second
+	defaultCacheMaxEntries = 10000
+)
+
+// CacheConfig определяет параметры кэширования решений о доступе
+type CacheConfig struct {
+	// Включает кэширование решений сервиса locator-ars
+	Enabled bool
+
+	// Время жизни решения "доступ разрешен"
+	// По умолчанию: 30 секунд, отрицательное значение отключает кэширование таких решений
+	AllowTTL time.Duration
+
+	// Время жизни решения "доступ запрещен"
+	// По умолчанию: 5 секунд, отрицательное значение отключает кэширование таких решений
+	DenyTTL time.Duration
+
+	// Максимальное количество записей в кэше, при превышении вытесняются
+	// давно не использованные записи (LRU)
+	// По умолчанию: 10000
+	MaxEntries int
+}
+
+// cacheEntry запись кэша решений
+type cacheEntry struct {
+	key       string
+	response  AccessResponse
+	expiresAt time.Time
+}
+
+// decisionCache потокобезопасный LRU-кэш решений о доступе с TTL
+type decisionCache struct {
+	mu         sync.Mutex
+	allowTTL   time.Duration
+	denyTTL    time.Duration
+	maxEntries int
+	items      map[string]*list.Element
+	order      *list.List
+}
+
+// newDecisionCache создает кэш решений, подставляя значения по умолчанию
+func newDecisionCache(config CacheConfig) *decisionCache {
+	if config.AllowTTL == 0 {
+		config.AllowTTL = defaultCacheAllowTTL
+	}
+	if config.DenyTTL == 0 {
+		config.DenyTTL = defaultCacheDenyTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultCacheMaxEntries
+	}
+
+	return &decisionCache{
+		allowTTL:   config.AllowTTL,
+		denyTTL:    config.DenyTTL,
+		maxEntries: config.MaxEntries,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// cacheKey формирует ключ кэша из действия и хэша Entitlements,
+// чтобы не хранить сами Entitlements в памяти
+func cacheKey(action, entitlements string) string {
+	sum := sha256.Sum256([]byte(entitlements))
+	return action + "\x00" + hex.EncodeToString(sum[:])
+}
+
+// get возвращает закэшированное решение, если оно есть и не устарело
+func (dc *decisionCache) get(key string) (AccessResponse, bool) {
+	response, _, ok := dc.lookup(key)
+	return response, ok
+}
+
+// lookup возвращает закэшированное решение и время его устаревания
+func (dc *decisionCache) lookup(key string) (AccessResponse, time.Time, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	element, ok := dc.items[key]
+	if !ok {
+		return AccessResponse{}, time.Time{}, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		dc.order.Remove(element)
+		delete(dc.items, key)
+		return AccessResponse{}, time.Time{}, false
+	}
+
+	dc.order.MoveToFront(element)
+	return entry.response, entry.expiresAt, true
+}
+
+// set сохраняет решение в кэше с TTL, зависящим от результата
+func (dc *decisionCache) set(key string, response AccessResponse) {
+	ttl := dc.denyTTL
+	if response.Allowed {
+		ttl = dc.allowTTL
+	}
+	if ttl < 0 {
+		return
+	}
+	dc.setUntil(key, response, time.Now().Add(ttl))
+}
+
+// setUntil сохраняет решение в кэше до expiresAt
+func (dc *decisionCache) setUntil(key string, response AccessResponse, expiresAt time.Time) {
+	if !time.Now().Before(expiresAt) {
+		return
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if element, ok := dc.items[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.response = response
+		entry.expiresAt = expiresAt
+		dc.order.MoveToFront(element)
+		return
+	}
+
+	dc.items[key] = dc.order.PushFront(&cacheEntry{
+		key:       key,
+		response:  response,
+		expiresAt: expiresAt,
+	})
+
+	for dc.order.Len() > dc.maxEntries {
+		oldest := dc.order.Back()
+		dc.order.Remove(oldest)
+		delete(dc.items, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,216 @@
+package locatorars
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDecisionCacheTTL(t *testing.T) {
+	cache := newDecisionCache(CacheConfig{AllowTTL: 50 * time.Millisecond, DenyTTL: time.Hour})
+	cache.set("allowed", AccessResponse{Allowed: true})
+	cache.set("denied", AccessResponse{Allowed: false})
+
+	if _, ok := cache.get("allowed"); !ok {
+		t.Fatal("allowed decision missing right after set")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := cache.get("allowed"); ok {
+		t.Error("allowed decision served after AllowTTL")
+	}
+	if _, ok := cache.get("denied"); !ok {
+		t.Error("denied decision expired before DenyTTL")
+	}
+	if _, ok := cache.items["allowed"]; ok {
+		t.Error("expired entry was not removed")
+	}
+}
+
+func TestDecisionCacheNegativeTTL(t *testing.T) {
+	cache := newDecisionCache(CacheConfig{AllowTTL: time.Hour, DenyTTL: -1})
+	cache.set("allowed", AccessResponse{Allowed: true})
+	cache.set("denied", AccessResponse{Allowed: false})
+
+	if _, ok := cache.get("allowed"); !ok {
+		t.Error("allowed decision was not cached")
+	}
+	if _, ok := cache.get("denied"); ok {
+		t.Error("denied decision was cached with negative DenyTTL")
+	}
+}
+
+func TestDecisionCacheEviction(t *testing.T) {
+	cache := newDecisionCache(CacheConfig{MaxEntries: 2})
+	cache.set("a", AccessResponse{Action: "a", Allowed: true})
+	cache.set("b", AccessResponse{Action: "b", Allowed: true})
+
+	// Обращение к "a" делает давно не использованной запись "b"
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("entry a missing")
+	}
+	cache.set("c", AccessResponse{Action: "c", Allowed: true})
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("least recently used entry b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("entry %s was evicted", key)
+		}
+	}
+	if n := cache.order.Len(); n != 2 {
+		t.Errorf("cache holds %d entries, want 2", n)
+	}
+
+	// Повторное сохранение обновляет запись, а не добавляет новую
+	cache.set("c", AccessResponse{Action: "c", Allowed: false})
+	if decision, _ := cache.get("c"); decision.Allowed || cache.order.Len() != 2 {
+		t.Errorf("entry c was not updated in place: %+v, %d entries", decision, cache.order.Len())
+	}
+}
+
+func TestAccessClientCacheIsolatesUser(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"allowed":true,"action":"reports.view","user":{"name":"alice"}}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.URL = server.URL
+	config.LogLevel = LogLevelNone
+	config.Cache.Enabled = true
+	client := NewAccessClient(config)
+
+	// Изменения пользователя в ответе не затрагивают кэш ни при промахе, ни при попадании
+	for i := 0; i < 3; i++ {
+		decision, err := client.CheckAccessDetailed(context.Background(), "reports.view", "reports")
+		if err != nil {
+			t.Fatalf("CheckAccessDetailed: %v", err)
+		}
+		if name := decision.User["name"]; name != "alice" {
+			t.Fatalf("check %d: user name %v, want alice", i, name)
+		}
+		decision.User["name"] = "mallory"
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
+
+func TestAccessClientCacheKeepsConfirmationTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"allowed":true,"action":"reports.view"}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.URL = server.URL
+	config.LogLevel = LogLevelNone
+	config.Cache.Enabled = true
+	client := NewAccessClient(config)
+
+	fresh, err := client.CheckAccessDetailed(context.Background(), "reports.view", "reports")
+	if err != nil {
+		t.Fatalf("CheckAccessDetailed: %v", err)
+	}
+	if fresh.confirmedAt.IsZero() {
+		t.Fatal("service response has no confirmation time")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	cached, err := client.CheckAccessDetailed(context.Background(), "reports.view", "reports")
+	if err != nil {
+		t.Fatalf("CheckAccessDetailed: %v", err)
+	}
+	if !cached.confirmedAt.Equal(fresh.confirmedAt) {
+		t.Errorf("cached decision confirmed at %v, want %v", cached.confirmedAt, fresh.confirmedAt)
+	}
+}
+
+func TestLastKnownGoodKeepsConfirmationTime(t *testing.T) {
+	store := newLastKnownGood(LastKnownGoodConfig{GraceWindow: time.Minute})
+	now := time.Now()
+
+	store.remember("a", AccessResponse{Allowed: true, confirmedAt: now.Add(-50 * time.Second)})
+	if _, age, ok := store.lookup("a"); !ok || age < 49*time.Second {
+		t.Fatalf("age %v, ok %v, want the age of the original response", age, ok)
+	}
+
+	// Более старое решение, например из кэша, не заменяет сохраненное
+	store.remember("a", AccessResponse{Allowed: false, confirmedAt: now.Add(-55 * time.Second)})
+	if decision, _, _ := store.lookup("a"); !decision.Allowed {
+		t.Error("older decision replaced a newer one")
+	}
+
+	// Решение старше GraceWindow не сохраняется
+	store.remember("b", AccessResponse{Allowed: true, confirmedAt: now.Add(-2 * time.Minute)})
+	if _, _, ok := store.lookup("b"); ok {
+		t.Error("expired decision was stored")
+	}
+
+	// Время решения checker, не являющегося AccessClient, неизвестно, оно считается свежим
+	store.remember("c", AccessResponse{Allowed: true})
+	if _, age, ok := store.lookup("c"); !ok || age > time.Second {
+		t.Errorf("age %v, ok %v, want a fresh decision", age, ok)
+	}
+}
+
+func TestServeStaleKeepsConfirmationTime(t *testing.T) {
+	const graceWindow = time.Minute
+
+	tests := []struct {
+		name      string
+		age       time.Duration
+		expr      bool
+		wantStale bool
+	}{
+		{name: "recent decision", age: graceWindow / 2, wantStale: true},
+		// Решение из кэша, полученное от сервиса раньше GraceWindow, не продлевается повторной выдачей
+		{name: "old cached decision", age: 2 * graceWindow},
+		{name: "expression of recent decisions", age: graceWindow / 2, expr: true, wantStale: true},
+		{name: "expression with an old cached decision", age: 2 * graceWindow, expr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failing atomic.Bool
+			confirmedAt := time.Now().Add(-tt.age)
+			checker := CheckerFunc(func(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+				if failing.Load() {
+					return nil, &StatusError{Code: http.StatusServiceUnavailable}
+				}
+				at := time.Now()
+				if action == "reports.view" {
+					at = confirmedAt
+				}
+				return &AccessResponse{Action: action, Allowed: true, confirmedAt: at}, nil
+			})
+
+			config := DefaultConfig()
+			config.LogLevel = LogLevelNone
+			config.LastKnownGood.GraceWindow = graceWindow
+			m := NewMiddlewareWithChecker(checker, config)
+
+			authorize := func() AuthResult {
+				if tt.expr {
+					return m.AuthorizeExpr(context.Background(), MustParseExpr("reports.list && reports.view"), "reports", ServeStale())
+				}
+				return m.Authorize(context.Background(), "reports.view", "reports", ServeStale())
+			}
+
+			if result := authorize(); result.Outcome != OutcomeAllowed {
+				t.Fatalf("outcome %v, want allowed", result.Outcome)
+			}
+			failing.Store(true)
+			result := authorize()
+			if got := result.Outcome == OutcomeStale; got != tt.wantStale {
+				t.Errorf("outcome %v, want stale: %v", result.Outcome, tt.wantStale)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package locatorars
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AccessChecker принимает решение о доступе к действию по Entitlements.
+// Реализуется AccessClient (сервис locator-ars) и LocalEvaluator (локальная политика).
+// Middleware работает с любой реализацией, см. NewMiddlewareWithChecker
+type AccessChecker interface {
+	// CheckAccessDetailed возвращает решение о доступе. Ошибка означает,
+	// что решение принять не удалось, политика AllowOnFailure при этом не применяется
+	CheckAccessDetailed(ctx context.Context, action, entitlements string) (*AccessResponse, error)
+}
+
+var (
+	_ AccessChecker = (*AccessClient)(nil)
+	_ AccessChecker = (*LocalEvaluator)(nil)
+)
+
+// CheckerFunc позволяет использовать функцию как AccessChecker, например в тестах
+type CheckerFunc func(ctx context.Context, action, entitlements string) (*AccessResponse, error)
+
+// CheckAccessDetailed вызывает f(ctx, action, entitlements)
+func (f CheckerFunc) CheckAccessDetailed(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+	return f(ctx, action, entitlements)
+}
+
+// CheckerDecorator оборачивает AccessChecker дополнительной функциональностью
+type CheckerDecorator func(next AccessChecker) AccessChecker
+
+// Decorate оборачивает checker декораторами. Первый декоратор оказывается внешним,
+// то есть Decorate(c, WithLogging(l), WithCache(cfg)) логирует и попадания в кэш
+func Decorate(checker AccessChecker, decorators ...CheckerDecorator) AccessChecker {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		checker = decorators[i](checker)
+	}
+	return checker
+}
+
+// batchChecker реализация AccessChecker с собственной пакетной проверкой действий
+type batchChecker interface {
+	CheckActions(ctx context.Context, entitlements string, actions []string) (map[string]bool, error)
+}
+
+// WithCache кэширует успешные решения обернутого checker. Параметр Enabled
+// игнорируется, TTL и размер кэша задаются как в Config.Cache. Ошибки не кэшируются
+func WithCache(config CacheConfig) CheckerDecorator {
+	return func(next AccessChecker) AccessChecker {
+		return &cachingChecker{next: next, cache: newDecisionCache(config)}
+	}
+}
+
+// cachingChecker декоратор кэширования решений
+type cachingChecker struct {
+	next  AccessChecker
+	cache *decisionCache
+}
+
+func (c *cachingChecker) CheckAccessDetailed(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+	key := cacheKey(action, entitlements)
+	if cached, ok := c.cache.get(key); ok {
+		// Копируем карту пользователя, чтобы изменения в обработчике не затронули кэш
+		cached.User = maps.Clone(cached.User)
+		return &cached, nil
+	}
+
+	decision, err := c.next.CheckAccessDetailed(ctx, action, entitlements)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := *decision
+	cached.User = maps.Clone(decision.User)
+	c.cache.set(key, cached)
+	return decision, nil
+}
+
+// WithLogging логирует каждое решение обернутого checker и время его получения
+func WithLogging(logger Logger) CheckerDecorator {
+	return func(next AccessChecker) AccessChecker {
+		return &loggingChecker{next: next, logger: logger}
+	}
+}
+
+// loggingChecker декоратор логирования решений
+type loggingChecker struct {
+	next   AccessChecker
+	logger Logger
+}
+
+func (c *loggingChecker) CheckAccessDetailed(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+	startTime := time.Now()
+	decision, err := c.next.CheckAccessDetailed(ctx, action, entitlements)
+	elapsedMs := time.Since(startTime).Milliseconds()
+
+	switch {
+	case err != nil:
+		c.logger.Error("Access check for action %s failed in %d ms: %v", action, elapsedMs, err)
+	case decision.Allowed:
+		c.logger.Debug("Access check for action %s: granted in %d ms", action, elapsedMs)
+	default:
+		c.logger.Debug("Access check for action %s: denied in %d ms", action, elapsedMs)
+	}
+	return decision, err
+}
+
+// WithMetrics учитывает каждую проверку обернутого checker в metrics как обращение
+// к эндпоинту EndpointChecker: статус 200 для полученного решения, HTTP-статус для
+// StatusError и 0 для остальных ошибок. Решения маршрутов учитывает Middleware,
+// поэтому один сборщик можно передать и в декоратор, и в Config.Metrics
+func WithMetrics(metrics Metrics) CheckerDecorator {
+	metrics = metricsOrNoop(metrics)
+
+	return func(next AccessChecker) AccessChecker {
+		return CheckerFunc(func(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+			startTime := time.Now()
+			decision, err := next.CheckAccessDetailed(ctx, action, entitlements)
+			metrics.ObserveRequest(EndpointChecker, checkStatus(err), time.Since(startTime))
+			return decision, err
+		})
+	}
+}
+
+// checkStatus возвращает статус проверки для Metrics.ObserveRequest
+func checkStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+	return 0
+}
+
+// WithRetry повторяет проверку при временных сбоях по правилам Config.Retry.
+// Если logger равен nil, повторы не логируются
+func WithRetry(config RetryConfig, logger Logger) CheckerDecorator {
+	if logger == nil {
+		logger = NewDefaultLogger(LogLevelNone)
+	}
+	policy := newRetryPolicy(config)
+
+	return func(next AccessChecker) AccessChecker {
+		return CheckerFunc(func(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+			var decision *AccessResponse
+			err := policy.do(ctx, logger, "action: "+action, func() error {
+				var err error
+				decision, err = next.CheckAccessDetailed(ctx, action, entitlements)
+				return err
+			})
+			return decision, err
+		})
+	}
+}
+
+// checkAllowed проверяет действие через checker и возвращает только признак доступа
+func checkAllowed(ctx context.Context, checker AccessChecker, action, entitlements string) (bool, error) {
+	decision, err := checker.CheckAccessDetailed(ctx, action, entitlements)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed, nil
+}
+
+// actionError ошибка проверки одного действия из нескольких
+type actionError struct {
+	action string
+	err    error
+}
+
+func (e *actionError) Error() string {
+	return fmt.Sprintf("action %s: %v", e.action, e.err)
+}
+
+func (e *actionError) Unwrap() error {
+	return e.err
+}
+
+// actionErrors возвращает ошибки отдельных действий из объединенной ошибки проверки
+func actionErrors(err error) map[string]error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil
+	}
+	failed := make(map[string]error)
+	for _, err := range joined.Unwrap() {
+		if aErr, ok := err.(*actionError); ok {
+			failed[aErr.action] = aErr.err
+		}
+	}
+	return failed
+}
+
+// checkParallel проверяет действия функцией check с ограничением параллельности
+// и заполняет results. Ошибки проверок объединяются
+func checkParallel(ctx context.Context, concurrency int, actions []string, results map[string]bool, check func(ctx context.Context, action string) (bool, error)) error {
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	semaphore := make(chan struct{}, concurrency)
+
+	for _, action := range actions {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(action string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			allowed, err := check(ctx, action)
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[action] = allowed
+			if err != nil {
+				errs = append(errs, &actionError{action: action, err: err})
+			}
+		}(action)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
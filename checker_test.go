@@ -0,0 +1,56 @@
+package locatorars
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics запоминает обращения, переданные в ObserveRequest
+type recordingMetrics struct {
+	noopMetrics
+
+	mu       sync.Mutex
+	requests []string
+	statuses []int
+}
+
+func (m *recordingMetrics) ObserveRequest(endpoint string, status int, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, endpoint)
+	m.statuses = append(m.statuses, status)
+}
+
+func TestWithMetrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	errFailed := errors.New("backend failed")
+
+	checker := Decorate(CheckerFunc(func(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+		switch action {
+		case "limited":
+			return nil, &StatusError{Code: http.StatusTooManyRequests}
+		case "broken":
+			return nil, errFailed
+		default:
+			return &AccessResponse{Action: action, Allowed: true}, nil
+		}
+	}), WithMetrics(metrics))
+
+	for _, action := range []string{"reports.view", "limited", "broken"} {
+		_, _ = checker.CheckAccessDetailed(context.Background(), action, "reports")
+	}
+
+	want := []int{http.StatusOK, http.StatusTooManyRequests, 0}
+	if len(metrics.statuses) != len(want) {
+		t.Fatalf("observed %d requests, want %d", len(metrics.statuses), len(want))
+	}
+	for i, status := range want {
+		if metrics.requests[i] != EndpointChecker || metrics.statuses[i] != status {
+			t.Errorf("request %d: endpoint %s, status %d, want %s, %d", i, metrics.requests[i], metrics.statuses[i], EndpointChecker, status)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Package chi предоставляет middleware проверки прав доступа locator-ars
+// для роутера chi. Chi использует стандартные net/http middleware, поэтому
+// пакет построен на адаптере net/http из корневого пакета и добавляет
+// вспомогательные функции для групп маршрутов.
+package chi
+
+import (
+	"net/http"
+
+	chiv5 "github.com/go-chi/chi/v5"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+)
+
+// DecisionFromRequest возвращает ответ сервиса, сохраненный middleware при разрешении доступа
+func DecisionFromRequest(r *http.Request) (*locatorars.AccessResponse, bool) {
+	return locatorars.DecisionFromRequest(r)
+}
+
+// RequireAction создает middleware, который требует указанное действие
+func RequireAction(m *locatorars.Middleware, action string, opts ...locatorars.RouteOption) func(http.Handler) http.Handler {
+	return m.RequireActionHTTP(action, opts...)
+}
+
+// RequireAny создает middleware, который требует хотя бы одно из указанных действий.
+// Повторы убираются, пустой список вызывает панику при регистрации маршрута
+func RequireAny(m *locatorars.Middleware, actions ...string) func(http.Handler) http.Handler {
+	return m.RequireAnyHTTP(actions...)
+}
+
+// RequireAll создает middleware, который требует все указанные действия.
+// Повторы убираются, пустой список вызывает панику при регистрации маршрута
+func RequireAll(m *locatorars.Middleware, actions ...string) func(http.Handler) http.Handler {
+	return m.RequireAllHTTP(actions...)
+}
+
+// RequireExpr создает middleware, который требует истинности логического выражения над действиями.
+// Синтаксическая ошибка выражения возвращается при создании middleware
+func RequireExpr(m *locatorars.Middleware, source string, opts ...locatorars.RouteOption) (func(http.Handler) http.Handler, error) {
+	return m.RequireExprHTTP(source, opts...)
+}
+
+// Group регистрирует группу маршрутов, защищенных действием action:
+//
+//	arschi.Group(r, arsMiddleware, "reports.view", func(r chi.Router) {
+//		r.Get("/reports", listReports)
+//		r.Get("/reports/{id}", getReport)
+//	})
+func Group(r chiv5.Router, m *locatorars.Middleware, action string, fn func(r chiv5.Router)) chiv5.Router {
+	return r.Group(func(r chiv5.Router) {
+		r.Use(RequireAction(m, action))
+		fn(r)
+	})
+}
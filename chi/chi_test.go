@@ -0,0 +1,40 @@
+package chi_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	chiv5 "github.com/go-chi/chi/v5"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	arschi "github.com/LT-Devs/locator-ars-go-lib/chi"
+	"github.com/LT-Devs/locator-ars-go-lib/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(m *locatorars.Middleware, action string) http.Handler {
+		r := chiv5.NewRouter()
+		arschi.Group(r, m, action, func(r chiv5.Router) {
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				if decision, ok := arschi.DecisionFromRequest(r); ok {
+					fmt.Fprint(w, decision.User["name"])
+				}
+			})
+		})
+		return r
+	})
+}
+
+func TestConformanceActions(t *testing.T) {
+	newHandler := func(require func(m *locatorars.Middleware, actions ...string) func(http.Handler) http.Handler) conformance.NewActionsHandler {
+		return func(m *locatorars.Middleware, actions ...string) http.Handler {
+			r := chiv5.NewRouter()
+			r.With(require(m, actions...)).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			return r
+		}
+	}
+	conformance.RunActions(t, newHandler(arschi.RequireAny), newHandler(arschi.RequireAll))
+}
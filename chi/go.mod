@@ -0,0 +1,24 @@
+module github.com/LT-Devs/locator-ars-go-lib/chi
+
+go 1.24
+
+require (
+	github.com/LT-Devs/locator-ars-go-lib v0.2.0
+	github.com/go-chi/chi/v5 v5.2.3
+)
+
+require (
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/pelletier/go-toml/v2 v2.0.8 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel v1.38.0 // indirect
+	go.opentelemetry.io/otel/metric v1.38.0 // indirect
+	go.opentelemetry.io/otel/trace v1.38.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// В рабочей копии репозитория модуль собирается с корневым модулем из соседнего каталога.
+// Зависимые модули replace не учитывают и получают версию из require,
+// поэтому корневой модуль тегируется раньше подмодулей (см. README, «Выпуск версий»)
+replace github.com/LT-Devs/locator-ars-go-lib => ../
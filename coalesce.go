@@ -0,0 +1,142 @@
+package locatorars
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+// flightGroup объединяет одновременные одинаковые проверки в один запрос к сервису
+type flightGroup struct {
+	logger Logger
+
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall выполняющийся запрос к сервису и ожидающие его результата проверки
+type flightCall struct {
+	done     chan struct{}
+	decision *AccessResponse
+	err      error
+
+	// waiters количество проверок, ожидающих результата, shared - всего присоединившихся.
+	// Изменяются под flightGroup.mu
+	waiters int
+	shared  int
+	cancel  context.CancelFunc
+
+	// deadline самый поздний дедлайн среди присоединившихся вызовов, bounded - дедлайн
+	// есть у каждого из них. Изменяются под flightGroup.mu
+	deadline time.Time
+	bounded  bool
+}
+
+// join учитывает дедлайн присоединившегося вызова. Вызывается под flightGroup.mu
+func (c *flightCall) join(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		c.bounded = false
+		return
+	}
+	if deadline.After(c.deadline) {
+		c.deadline = deadline
+	}
+}
+
+// flightContext контекст общего запроса. Его дедлайн - самый поздний среди дедлайнов
+// присоединившихся вызовов, поэтому повторы не ждут дольше, чем готов ждать
+// последний из них. Сам дедлайн соблюдается через leave: когда он истекает,
+// результата больше никто не ждет и запрос отменяется
+type flightContext struct {
+	context.Context
+	group *flightGroup
+	call  *flightCall
+}
+
+// Deadline возвращает текущий дедлайн общего запроса
+func (c *flightContext) Deadline() (time.Time, bool) {
+	c.group.mu.Lock()
+	defer c.group.mu.Unlock()
+	return c.call.deadline, c.call.bounded
+}
+
+// do выполняет fetch один раз для всех одновременных вызовов с ключом key.
+// Запрос выполняется с собственным контекстом, поэтому отмена одного вызова не прерывает
+// его для остальных: вызов с отмененным ctx сразу возвращает ошибку контекста, а запрос
+// отменяется, только когда результата больше никто не ждет. Дедлайн запроса - самый
+// поздний из дедлайнов вызовов.
+// shared равен true, если вызов присоединился к уже выполняющемуся запросу.
+// target описывает проверку в логах
+func (g *flightGroup) do(ctx context.Context, key, target string, fetch func(ctx context.Context) (*AccessResponse, error)) (decision *AccessResponse, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call, shared := g.calls[key]
+	if shared {
+		call.waiters++
+		call.shared++
+		call.join(ctx)
+		g.logger.Debug("Joining in-flight access check for %s", target)
+	} else {
+		// Значения контекста (спан трассировки) сохраняются, отмена - нет
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall{done: make(chan struct{}), waiters: 1, shared: 1, cancel: cancel, bounded: true}
+		call.join(ctx)
+		g.calls[key] = call
+		go g.run(&flightContext{Context: callCtx, group: g, call: call}, key, target, call, fetch)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, shared, call.err
+		}
+		// Каждый вызов получает свою копию, чтобы изменения в обработчике не затронули другие
+		decision := *call.decision
+		decision.User = maps.Clone(call.decision.User)
+		return &decision, shared, nil
+	case <-ctx.Done():
+		g.leave(key, call)
+		// Оборачиваем так же, как ошибку отмененного HTTP-запроса
+		return nil, shared, &transportError{err: ctx.Err()}
+	}
+}
+
+// run выполняет запрос и передает результат ожидающим вызовам
+func (g *flightGroup) run(ctx context.Context, key, target string, call *flightCall, fetch func(ctx context.Context) (*AccessResponse, error)) {
+	defer call.cancel()
+	decision, err := fetch(ctx)
+
+	g.mu.Lock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	call.decision, call.err = decision, err
+	shared := call.shared
+	g.mu.Unlock()
+
+	close(call.done)
+	if shared > 1 {
+		g.logger.Info("Coalesced %d concurrent access checks for %s into one request", shared, target)
+	}
+}
+
+// leave отсоединяет вызов, отмененный вызывающей стороной. Если результата больше
+// никто не ждет, запрос отменяется, а следующие вызовы начнут новый
+func (g *flightGroup) leave(key string, call *flightCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	call.cancel()
+}
@@ -0,0 +1,61 @@
+package locatorars_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+func TestCoalescedChecksShareOneRequest(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	s.AddRule(locatorarstest.Rule{Action: "reports.view", Allowed: true, Latency: 100 * time.Millisecond})
+	client := locatorars.NewAccessClient(s.Config())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if allowed, err := client.CheckAccessContext(context.Background(), "reports.view", "reports.view"); err != nil || !allowed {
+				t.Errorf("allowed %v, err %v", allowed, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := s.Calls("reports.view"); calls != 1 {
+		t.Fatalf("service called %d times, want 1", calls)
+	}
+}
+
+func TestCoalescedCheckKeepsRetryBudget(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	s.Fail("reports.view", http.StatusServiceUnavailable)
+
+	config := s.Config()
+	config.Retry = locatorars.RetryConfig{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Second, Multiplier: 1}
+	client := locatorars.NewAccessClient(config)
+
+	// Повтор через секунду не укладывается в дедлайн вызова, поэтому вызов
+	// сразу получает ошибку сервиса, а не ждет истечения дедлайна
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	_, err := client.CheckAccessDetailed(ctx, "reports.view", "reports.view")
+	var statusErr *locatorars.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("err %v, want 503 status error", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("check waited for the caller deadline instead of skipping the retry")
+	}
+	if calls := s.Calls("reports.view"); calls != 1 {
+		t.Fatalf("service called %d times, want 1", calls)
+	}
+}
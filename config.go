@@ -2,7 +2,13 @@ package locatorars
 
 import (
 	"log"
+	"net/http"
 	"os"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // LogLevel определяет уровень логирования
@@ -29,26 +35,27 @@ type Logger interface {
 // DefaultLogger реализация логгера по умолчанию
 type DefaultLogger struct {
 	logger *log.Logger
-	level  LogLevel
+	// level хранится атомарно, чтобы SetLogLevel можно было вызывать во время обработки запросов
+	level atomic.Int32
 }
 
 // Debug логирует отладочное сообщение
 func (l *DefaultLogger) Debug(format string, args ...interface{}) {
-	if l.level >= LogLevelDebug {
+	if LogLevel(l.level.Load()) >= LogLevelDebug {
 		l.logger.Printf("[DEBUG] "+format, args...)
 	}
 }
 
 // Info логирует информационное сообщение
 func (l *DefaultLogger) Info(format string, args ...interface{}) {
-	if l.level >= LogLevelInfo {
+	if LogLevel(l.level.Load()) >= LogLevelInfo {
 		l.logger.Printf("[INFO] "+format, args...)
 	}
 }
 
 // Error логирует сообщение об ошибке
 func (l *DefaultLogger) Error(format string, args ...interface{}) {
-	if l.level >= LogLevelError {
+	if LogLevel(l.level.Load()) >= LogLevelError {
 		l.logger.Printf("[ERROR] "+format, args...)
 	}
 }
@@ -60,15 +67,79 @@ type Config struct {
 	URL string
 
 	// Политика действий в случае недоступности сервиса проверки прав
-	// true - разрешить доступ если сервис недоступен, 
+	// true - разрешить доступ если сервис недоступен,
 	// false - запретить доступ если сервис недоступен
 	AllowOnFailure bool
-	
+
 	// Уровень логирования
 	LogLevel LogLevel
-	
+
 	// Пользовательский логгер (если nil, будет использован логгер по умолчанию)
 	Logger Logger
+
+	// Кэширование решений о доступе (по умолчанию выключено)
+	Cache CacheConfig
+
+	// Таймаут запроса к сервису locator-ars
+	// По умолчанию: 5 секунд
+	Timeout time.Duration
+
+	// Пользовательский HTTP-клиент (если задан, Timeout, Transport и TLS игнорируются)
+	HTTPClient *http.Client
+
+	// Пользовательский транспорт HTTP-клиента (несовместим с TLS)
+	Transport http.RoundTripper
+
+	// Параметры TLS и mTLS для соединения с сервисом locator-ars
+	TLS TLSConfig
+
+	// Политика повторных запросов при временных сбоях (по умолчанию без повторов)
+	Retry RetryConfig
+
+	// Автоматический выключатель для быстрого отказа при недоступности сервиса (по умолчанию выключен)
+	CircuitBreaker CircuitBreakerConfig
+
+	// URL пакетного эндпоинта для проверки нескольких действий одним запросом.
+	// Если не задан или сервис его не поддерживает, действия проверяются по одному
+	BatchURL string
+
+	// Максимальное количество параллельных одиночных проверок в CheckActions
+	// По умолчанию: 4
+	BatchConcurrency int
+
+	// Источник Entitlements во входящих запросах
+	// (если nil, используется заголовок X-Authentik-Entitlements)
+	EntitlementExtractor EntitlementExtractor
+
+	// Режим принятия решений: ModeRemote, ModeLocal или ModeLocalFirst
+	// По умолчанию: ModeRemote
+	Mode EvaluationMode
+
+	// Файл локальной политики (YAML или JSON) для режимов ModeLocal и ModeLocalFirst
+	LocalPolicyFile string
+
+	// Готовый локальный вычислитель (если задан, LocalPolicyFile игнорируется)
+	LocalEvaluator *LocalEvaluator
+
+	// Сборщик метрик решений, обращений к сервису, кэша и выключателя (по умолчанию не используется)
+	Metrics Metrics
+
+	// Провайдер трассировки OpenTelemetry (если nil, используется глобальный otel.GetTracerProvider())
+	TracerProvider trace.TracerProvider
+
+	// Пропагатор контекста трассировки в запросы к сервису locator-ars
+	// По умолчанию: W3C Trace Context
+	Propagator propagation.TextMapPropagator
+
+	// Приемник журнала аудита решений о доступе (по умолчанию не используется)
+	AuditSink AuditSink
+
+	// Отключает объединение одновременных одинаковых проверок в один запрос к сервису
+	DisableCoalescing bool
+
+	// Последние подтвержденные решения, применяемые при недоступности сервиса (по умолчанию выключено).
+	// GraceWindow и MaxEntries также ограничивают решения маршрутов с опцией ServeStale
+	LastKnownGood LastKnownGoodConfig
 }
 
 // DefaultConfig возвращает конфигурацию по умолчанию
@@ -78,13 +149,38 @@ func DefaultConfig() Config {
 		AllowOnFailure: false,
 		LogLevel:       LogLevelError,
 		Logger:         nil,
+		Cache: CacheConfig{
+			Enabled:    false,
+			AllowTTL:   defaultCacheAllowTTL,
+			DenyTTL:    defaultCacheDenyTTL,
+			MaxEntries: defaultCacheMaxEntries,
+		},
+		Timeout: defaultTimeout,
+		Retry: RetryConfig{
+			MaxAttempts:    1,
+			InitialBackoff: defaultRetryInitialBackoff,
+			MaxBackoff:     defaultRetryMaxBackoff,
+			Multiplier:     defaultRetryMultiplier,
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			Enabled:             false,
+			FailureThreshold:    defaultBreakerFailureThreshold,
+			CoolDown:            defaultBreakerCoolDown,
+			HalfOpenMaxRequests: defaultBreakerHalfOpenMaxRequests,
+		},
+		LastKnownGood: LastKnownGoodConfig{
+			Enabled:     false,
+			GraceWindow: defaultLastKnownGoodGraceWindow,
+			MaxEntries:  defaultLastKnownGoodMaxEntries,
+		},
+		BatchConcurrency: defaultBatchConcurrency,
+		Mode:             ModeRemote,
 	}
 }
 
 // NewDefaultLogger создает логгер по умолчанию с указанным уровнем
 func NewDefaultLogger(level LogLevel) Logger {
-	return &DefaultLogger{
-		logger: log.New(os.Stdout, "", log.LstdFlags),
-		level:  level,
-	}
-} 
\ No newline at end of file
+	logger := &DefaultLogger{logger: log.New(os.Stdout, "", log.LstdFlags)}
+	logger.level.Store(int32(level))
+	return logger
+}
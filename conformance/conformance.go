@@ -0,0 +1,270 @@
+// Package conformance содержит общий набор тестов для адаптеров locatorars
+// к веб-фреймворкам. Набор проверяет, что адаптер ведет себя так же,
+// как RequireAction для gin: извлекает Entitlements, применяет политику
+// AllowOnFailure, возвращает те же статусы и JSON-тела и передает ответ
+// сервиса обработчику.
+//
+// Использование в тестах адаптера:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, func(m *locatorars.Middleware, action string) http.Handler {
+//			// Построить приложение фреймворка с маршрутом "/", защищенным действием action
+//		})
+//	}
+//
+// RunActions аналогично проверяет RequireAny и RequireAll адаптера.
+package conformance
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+// Действия, которые понимает фейковый сервис locator-ars набора
+const (
+	// ActionAllowed - сервис разрешает доступ и возвращает пользователя UserName
+	ActionAllowed = "conformance.allowed"
+	// ActionDenied - сервис запрещает доступ
+	ActionDenied = "conformance.denied"
+	// ActionError - сервис отвечает статусом 500
+	ActionError = "conformance.error"
+)
+
+// UserName имя пользователя в ответе сервиса для ActionAllowed
+const UserName = "conformance-user"
+
+// NewHandler строит http.Handler приложения фреймворка с маршрутом GET "/",
+// защищенным адаптером для действия action. Защищенный обработчик должен
+// ответить статусом 200 и телом, равным значению User["name"] из ответа сервиса,
+// полученного через адаптер, или пустым телом, если ответа нет
+type NewHandler func(m *locatorars.Middleware, action string) http.Handler
+
+// NewActionsHandler строит http.Handler приложения фреймворка с маршрутом GET "/",
+// защищенным RequireAny или RequireAll адаптера для действий actions.
+// Защищенный обработчик должен ответить статусом 200
+type NewActionsHandler func(m *locatorars.Middleware, actions ...string) http.Handler
+
+// Run запускает набор тестов для адаптера
+func Run(t *testing.T, newHandler NewHandler) {
+	t.Helper()
+
+	ars := locatorarstest.NewServer(t)
+	ars.AllowUser(ActionAllowed, map[string]interface{}{"name": UserName})
+	ars.Fail(ActionError, http.StatusInternalServerError)
+
+	newMiddleware := func(allowOnFailure bool) *locatorars.Middleware {
+		config := ars.Config()
+		config.AllowOnFailure = allowOnFailure
+		return locatorars.NewMiddleware(config)
+	}
+
+	cases := []struct {
+		name           string
+		action         string
+		entitlements   string
+		allowOnFailure bool
+		wantStatus     int
+		wantBody       string
+		wantError      string
+	}{
+		{
+			name:         "allowed",
+			action:       ActionAllowed,
+			entitlements: "reports",
+			wantStatus:   http.StatusOK,
+			wantBody:     UserName,
+		},
+		{
+			name:         "denied",
+			action:       ActionDenied,
+			entitlements: "reports",
+			wantStatus:   http.StatusForbidden,
+			wantError:    "Access denied",
+		},
+		{
+			name:       "missing entitlements",
+			action:     ActionAllowed,
+			wantStatus: http.StatusUnauthorized,
+			wantError:  "Missing " + locatorars.EntitlementsHeader + " header",
+		},
+		{
+			name:         "service error fails closed",
+			action:       ActionError,
+			entitlements: "reports",
+			wantStatus:   http.StatusInternalServerError,
+			wantError:    "Failed to check access",
+		},
+		{
+			name:           "service error fails open",
+			action:         ActionError,
+			entitlements:   "reports",
+			allowOnFailure: true,
+			wantStatus:     http.StatusOK,
+			wantBody:       "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ars.ResetRequests()
+			handler := newHandler(newMiddleware(tc.allowOnFailure), tc.action)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.entitlements != "" {
+				req.Header.Set(locatorars.EntitlementsHeader, tc.entitlements)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			body, _ := io.ReadAll(rec.Body)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d, body: %s", rec.Code, tc.wantStatus, body)
+			}
+
+			if tc.wantError == "" {
+				if got := strings.TrimSpace(string(body)); got != tc.wantBody {
+					t.Errorf("body = %q, want %q", got, tc.wantBody)
+				}
+			} else {
+				if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+					t.Errorf("Content-Type = %q, want application/json", ct)
+				}
+				var payload map[string]interface{}
+				if err := json.Unmarshal(body, &payload); err != nil {
+					t.Fatalf("error body is not JSON: %v, body: %s", err, body)
+				}
+				if payload["error"] != tc.wantError {
+					t.Errorf("error = %v, want %q", payload["error"], tc.wantError)
+				}
+			}
+
+			received := ars.Requests()
+			if tc.entitlements == "" {
+				if len(received) != 0 {
+					t.Errorf("access service was called %d times without entitlements", len(received))
+				}
+				return
+			}
+			if len(received) != 1 || received[0].Entitlements != tc.entitlements {
+				t.Errorf("access service received %+v, want one call with entitlements %q", received, tc.entitlements)
+			}
+		})
+	}
+}
+
+// RunActions запускает набор тестов для RequireAny и RequireAll адаптера: пустой список действий
+// вызывает панику при регистрации маршрута, повторяющиеся действия проверяются один раз,
+// ответ 403 перечисляет запрещенные действия
+func RunActions(t *testing.T, requireAny, requireAll NewActionsHandler) {
+	t.Helper()
+
+	ars := locatorarstest.NewServer(t)
+	ars.Allow(ActionAllowed)
+	ars.Fail(ActionError, http.StatusInternalServerError)
+	m := locatorars.NewMiddleware(ars.Config())
+
+	builders := []struct {
+		name       string
+		newHandler NewActionsHandler
+	}{
+		{name: "RequireAny", newHandler: requireAny},
+		{name: "RequireAll", newHandler: requireAll},
+	}
+	for _, b := range builders {
+		t.Run(b.name+"/empty actions", func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("registration with an empty action list did not panic")
+				}
+			}()
+			b.newHandler(m)
+		})
+	}
+
+	cases := []struct {
+		name        string
+		all         bool
+		actions     []string
+		wantStatus  int
+		wantMissing []string
+		wantCalls   map[string]int
+	}{
+		{
+			name:       "RequireAny/allowed",
+			actions:    []string{ActionDenied, ActionAllowed},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "RequireAny/denied with duplicates",
+			actions:     []string{ActionDenied, ActionDenied},
+			wantStatus:  http.StatusForbidden,
+			wantMissing: []string{ActionDenied},
+			wantCalls:   map[string]int{ActionDenied: 1},
+		},
+		{
+			name:       "RequireAll/allowed with duplicates",
+			all:        true,
+			actions:    []string{ActionAllowed, ActionAllowed},
+			wantStatus: http.StatusOK,
+			wantCalls:  map[string]int{ActionAllowed: 1},
+		},
+		{
+			name:        "RequireAll/denied",
+			all:         true,
+			actions:     []string{ActionAllowed, ActionDenied},
+			wantStatus:  http.StatusForbidden,
+			wantMissing: []string{ActionDenied},
+		},
+		{
+			name:       "RequireAll/service error",
+			all:        true,
+			actions:    []string{ActionAllowed, ActionError},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ars.ResetRequests()
+			newHandler := requireAny
+			if tc.all {
+				newHandler = requireAll
+			}
+			handler := newHandler(m, tc.actions...)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(locatorars.EntitlementsHeader, "reports")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			body, _ := io.ReadAll(rec.Body)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d, body: %s", rec.Code, tc.wantStatus, body)
+			}
+			if tc.wantMissing != nil {
+				var payload struct {
+					Missing []string `json:"missing"`
+				}
+				if err := json.Unmarshal(body, &payload); err != nil {
+					t.Fatalf("error body is not JSON: %v, body: %s", err, body)
+				}
+				if !slices.Equal(payload.Missing, tc.wantMissing) {
+					t.Errorf("missing = %v, want %v", payload.Missing, tc.wantMissing)
+				}
+			}
+			for action, want := range tc.wantCalls {
+				if got := ars.Calls(action); got != want {
+					t.Errorf("access service checked %s %d times, want %d", action, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package locatorars
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EntitlementsHeader заголовок, из которого адаптеры извлекают Entitlements от Authentik
+const EntitlementsHeader = "X-Authentik-Entitlements"
+
+// Outcome итог проверки доступа, не зависящий от веб-фреймворка
+type Outcome int
+
+const (
+	// OutcomeAllowed - доступ разрешен сервисом
+	OutcomeAllowed Outcome = iota
+	// OutcomeFailOpen - проверить доступ не удалось, доступ разрешен политикой AllowOnFailure
+	OutcomeFailOpen
+	// OutcomeDenied - доступ запрещен
+	OutcomeDenied
+	// OutcomeUnauthorized - в запросе нет Entitlements
+	OutcomeUnauthorized
+	// OutcomeError - проверить доступ не удалось, доступ запрещен политикой AllowOnFailure
+	OutcomeError
+	// OutcomeTimeout - истек дедлайн контекста входящего запроса
+	OutcomeTimeout
+	// OutcomeCancelled - клиент отменил входящий запрос
+	OutcomeCancelled
+	// OutcomeStale - проверить доступ не удалось, доступ разрешен последним
+	// известным решением (опция маршрута ServeStale)
+	OutcomeStale
+)
+
+// String возвращает название итога проверки
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeAllowed:
+		return "allowed"
+	case OutcomeFailOpen:
+		return "fail-open"
+	case OutcomeDenied:
+		return "denied"
+	case OutcomeUnauthorized:
+		return "unauthorized"
+	case OutcomeError:
+		return "error"
+	case OutcomeTimeout:
+		return "timeout"
+	case OutcomeCancelled:
+		return "cancelled"
+	case OutcomeStale:
+		return "stale"
+	default:
+		return "unknown"
+	}
+}
+
+// AuthResult результат проверки доступа для адаптеров веб-фреймворков.
+// Адаптер пропускает запрос, если Allowed возвращает true, иначе отвечает
+// кодом StatusCode и телом Body (для OutcomeCancelled отвечать не нужно)
+type AuthResult struct {
+	// Итог проверки
+	Outcome Outcome
+
+	// Ответ сервиса, разрешивший доступ. Может быть nil, например при OutcomeFailOpen.
+	// При OutcomeStale содержит последнее известное решение
+	Decision *AccessResponse
+
+	// Действия, в которых было отказано (для RequireAny и RequireAll)
+	Missing []string
+
+	// Ошибка проверки, если она была
+	Err error
+}
+
+// Allowed возвращает true, если запрос следует пропустить
+func (r AuthResult) Allowed() bool {
+	return r.Outcome == OutcomeAllowed || r.Outcome == OutcomeFailOpen || r.Outcome == OutcomeStale
+}
+
+// StatusCode возвращает HTTP-статус ответа при отказе
+func (r AuthResult) StatusCode() int {
+	switch r.Outcome {
+	case OutcomeAllowed, OutcomeFailOpen, OutcomeStale:
+		return http.StatusOK
+	case OutcomeDenied:
+		return http.StatusForbidden
+	case OutcomeUnauthorized:
+		return http.StatusUnauthorized
+	case OutcomeTimeout:
+		return http.StatusGatewayTimeout
+	case OutcomeCancelled:
+		// Нестандартный статус nginx "Client Closed Request", используется только в логах
+		return 499
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Body возвращает JSON-тело ответа при отказе
+func (r AuthResult) Body() map[string]interface{} {
+	var message string
+	switch r.Outcome {
+	case OutcomeAllowed, OutcomeFailOpen, OutcomeStale:
+		return nil
+	case OutcomeDenied:
+		message = "Access denied"
+	case OutcomeUnauthorized:
+		// Для заголовка сохраняется сообщение предыдущих версий, для других источников оно общее
+		var headerErr *missingHeaderError
+		if errors.As(r.Err, &headerErr) {
+			message = "Missing " + headerErr.header + " header"
+		} else {
+			message = "Missing or invalid entitlements"
+		}
+	case OutcomeTimeout:
+		message = "Access check timed out"
+	case OutcomeCancelled:
+		message = "Access check cancelled"
+	default:
+		message = "Failed to check access"
+	}
+
+	body := map[string]interface{}{"error": message}
+	if len(r.Missing) > 0 {
+		body["missing"] = r.Missing
+	}
+	return body
+}
+
+// Authorize проверяет право на действие и возвращает итог, не зависящий от фреймворка.
+// Это ядро RequireAction, на котором построены адаптеры для gin и net/http.
+// Опции маршрута, например FailClosed(), переопределяют политику AllowOnFailure
+func (m *Middleware) Authorize(ctx context.Context, action, entitlements string, opts ...RouteOption) AuthResult {
+	return m.snapshot().authorize(ctx, action, entitlements, newRouteOptions(opts))
+}
+
+// authorize реализует Authorize на зафиксированной конфигурации
+func (m *Middleware) authorize(ctx context.Context, action, entitlements string, options routeOptions) (result AuthResult) {
+	startTime := time.Now()
+	ctx, span := m.startAuthorizeSpan(ctx, action)
+	defer func() { m.observe(ctx, span, action, startTime, result) }()
+
+	target := "action: " + action
+	m.logger.Debug("Checking access for %s", target)
+
+	if entitlements == "" {
+		return m.unauthorized()
+	}
+
+	decision, err := m.checker.CheckAccessDetailed(ctx, action, entitlements)
+	if err != nil {
+		return m.routeFailure(ctx, options, target, action, entitlements, err)
+	}
+	m.remember(options, action, entitlements, *decision)
+
+	if !decision.Allowed {
+		m.logger.Info("Access denied for %s", target)
+		return AuthResult{Outcome: OutcomeDenied, Decision: decision}
+	}
+
+	m.logger.Info("Access granted for %s", target)
+	return AuthResult{Outcome: OutcomeAllowed, Decision: decision}
+}
+
+// AuthorizeAny разрешает доступ, если разрешено хотя бы одно из действий.
+// Действия проверяются параллельно, проверка завершается при первом разрешающем решении.
+// Опции маршрута не принимаются, так как действия передаются переменным числом аргументов:
+// при отказе сервиса действует AllowOnFailure. Для своей политики отказа используйте
+// AuthorizeExpr с выражением "a || b"
+func (m *Middleware) AuthorizeAny(ctx context.Context, entitlements string, actions ...string) AuthResult {
+	return m.snapshot().authorizeAny(ctx, entitlements, actions...)
+}
+
+// authorizeAny реализует AuthorizeAny на зафиксированной конфигурации
+func (m *Middleware) authorizeAny(ctx context.Context, entitlements string, actions ...string) (result AuthResult) {
+	startTime := time.Now()
+	label := strings.Join(actions, " || ")
+	ctx, span := m.startAuthorizeSpan(ctx, label)
+	defer func() { m.observe(ctx, span, label, startTime, result) }()
+
+	target := "any of: " + strings.Join(actions, ", ")
+	m.logger.Debug("Checking access for %s", target)
+
+	if entitlements == "" {
+		return m.unauthorized()
+	}
+	if len(actions) == 0 {
+		// Пустой список действий не должен приводить к разрешению доступа
+		return AuthResult{Outcome: OutcomeDenied}
+	}
+
+	results := m.checkConcurrently(ctx, entitlements, actions, func(r actionResult) bool {
+		return r.err == nil && r.allowed
+	})
+
+	var missing []string
+	var firstErr error
+	for _, r := range results {
+		if r.err == nil && r.allowed {
+			m.logger.Info("Access granted for %s by action: %s", target, r.action)
+			return AuthResult{Outcome: OutcomeAllowed}
+		}
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		missing = append(missing, r.action)
+	}
+
+	if firstErr != nil {
+		return m.failure(ctx, target, firstErr)
+	}
+
+	m.logger.Info("Access denied for %s", target)
+	return AuthResult{Outcome: OutcomeDenied, Missing: missing}
+}
+
+// AuthorizeAll разрешает доступ, только если разрешены все действия.
+// Действия проверяются параллельно, проверка завершается при первом запрещающем решении,
+// поэтому Missing содержит действия, запрет на которые был получен к этому моменту.
+// Как и AuthorizeAny, не принимает опции маршрута, вместо них используйте AuthorizeExpr с "a && b"
+func (m *Middleware) AuthorizeAll(ctx context.Context, entitlements string, actions ...string) AuthResult {
+	return m.snapshot().authorizeAll(ctx, entitlements, actions...)
+}
+
+// authorizeAll реализует AuthorizeAll на зафиксированной конфигурации
+func (m *Middleware) authorizeAll(ctx context.Context, entitlements string, actions ...string) (result AuthResult) {
+	startTime := time.Now()
+	label := strings.Join(actions, " && ")
+	ctx, span := m.startAuthorizeSpan(ctx, label)
+	defer func() { m.observe(ctx, span, label, startTime, result) }()
+
+	target := "all of: " + strings.Join(actions, ", ")
+	m.logger.Debug("Checking access for %s", target)
+
+	if entitlements == "" {
+		return m.unauthorized()
+	}
+	if len(actions) == 0 {
+		// Пустой список действий не должен приводить к разрешению доступа
+		return AuthResult{Outcome: OutcomeDenied}
+	}
+
+	results := m.checkConcurrently(ctx, entitlements, actions, func(r actionResult) bool {
+		return r.err == nil && !r.allowed
+	})
+
+	var missing []string
+	var firstErr error
+	for _, r := range results {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		if !r.allowed {
+			missing = append(missing, r.action)
+		}
+	}
+
+	if len(missing) > 0 {
+		m.logger.Info("Access denied for %s, missing: %s", target, strings.Join(missing, ", "))
+		return AuthResult{Outcome: OutcomeDenied, Missing: missing}
+	}
+	if firstErr != nil {
+		return m.failure(ctx, target, firstErr)
+	}
+
+	m.logger.Info("Access granted for %s", target)
+	return AuthResult{Outcome: OutcomeAllowed}
+}
+
+// AuthorizeExpr разрешает доступ, если истинно логическое выражение над действиями
+func (m *Middleware) AuthorizeExpr(ctx context.Context, expr *Expr, entitlements string, opts ...RouteOption) AuthResult {
+	return m.snapshot().authorizeExpr(ctx, expr, entitlements, newRouteOptions(opts))
+}
+
+// authorizeExpr реализует AuthorizeExpr на зафиксированной конфигурации
+func (m *Middleware) authorizeExpr(ctx context.Context, expr *Expr, entitlements string, options routeOptions) (result AuthResult) {
+	startTime := time.Now()
+	ctx, span := m.startAuthorizeSpan(ctx, expr.String())
+	defer func() { m.observe(ctx, span, expr.String(), startTime, result) }()
+
+	target := "expression: " + expr.String()
+	m.logger.Debug("Checking access for %s", target)
+
+	if entitlements == "" {
+		return m.unauthorized()
+	}
+
+	allowed, confirmedAt, err := checkExpr(ctx, m.checker, expr, entitlements)
+	if err != nil {
+		return m.routeFailure(ctx, options, target, expr.String(), entitlements, err)
+	}
+	m.remember(options, expr.String(), entitlements, AccessResponse{Action: expr.String(), Allowed: allowed, confirmedAt: confirmedAt})
+
+	if !allowed {
+		m.logger.Info("Access denied for %s", target)
+		return AuthResult{Outcome: OutcomeDenied}
+	}
+
+	m.logger.Info("Access granted for %s", target)
+	return AuthResult{Outcome: OutcomeAllowed}
+}
+
+// startAuthorizeSpan начинает спан проверки маршрута
+func (m *Middleware) startAuthorizeSpan(ctx context.Context, action string) (context.Context, trace.Span) {
+	return m.tracer.Start(ctx, "locatorars.Authorize", trace.WithAttributes(AttrAction.String(action)))
+}
+
+// observe записывает итог проверки маршрута в метрики, журнал аудита и спан
+func (m *Middleware) observe(ctx context.Context, span trace.Span, action string, startTime time.Time, result AuthResult) {
+	m.recordDecision(ctx, action, startTime, result)
+	endAuthorizeSpan(span, result)
+}
+
+// recordDecision записывает итог проверки в метрики и журнал аудита
+func (m *Middleware) recordDecision(ctx context.Context, action string, startTime time.Time, result AuthResult) {
+	m.metrics.ObserveDecision(action, result.Outcome)
+	m.audit(ctx, action, startTime, result)
+}
+
+// unauthorized формирует результат для запроса без Entitlements
+func (m *Middleware) unauthorized() AuthResult {
+	if header, ok := m.extractor.(headerExtractor); ok {
+		m.logger.Info("Missing %s header in request", string(header))
+		return AuthResult{Outcome: OutcomeUnauthorized, Err: &missingHeaderError{header: string(header)}}
+	}
+	m.logger.Info("Missing entitlements in request")
+	return AuthResult{Outcome: OutcomeUnauthorized, Err: ErrMissingEntitlements}
+}
+
+// failure формирует результат для неудачной проверки с учетом отмены
+// входящего запроса и политики AllowOnFailure
+func (m *Middleware) failure(ctx context.Context, target string, err error) AuthResult {
+	outcome := failureOutcome(ctx, m.config.AllowOnFailure)
+	switch outcome {
+	case OutcomeTimeout, OutcomeCancelled:
+		m.logger.Info("Access check cancelled for %s: %v", target, ctx.Err())
+	case OutcomeFailOpen:
+		m.logger.Error("Error checking access for %s: %v", target, err)
+		m.logger.Info("Access allowed on failure due to configuration")
+	default:
+		m.logger.Error("Error checking access for %s: %v", target, err)
+	}
+	return AuthResult{Outcome: outcome, Err: err}
+}
+
+// actionResult результат проверки одного действия
+type actionResult struct {
+	action  string
+	allowed bool
+	err     error
+}
+
+// checkConcurrently проверяет действия параллельно и возвращает полученные результаты.
+// Как только decisive возвращает true, оставшиеся проверки отменяются
+func (m *Middleware) checkConcurrently(ctx context.Context, entitlements string, actions []string, decisive func(actionResult) bool) []actionResult {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Буфер на все действия, чтобы отмененные проверки не блокировались на отправке
+	resultCh := make(chan actionResult, len(actions))
+	for _, action := range actions {
+		go func(action string) {
+			// При ошибке решение по политике AllowOnFailure принимается
+			// по совокупности результатов, поэтому allowed здесь не учитывается
+			allowed, err := checkAllowed(ctx, m.checker, action, entitlements)
+			resultCh <- actionResult{action: action, allowed: allowed, err: err}
+		}(action)
+	}
+
+	results := make([]actionResult, 0, len(actions))
+	for range actions {
+		r := <-resultCh
+		results = append(results, r)
+		if decisive(r) {
+			break
+		}
+	}
+	return results
+}
+
+// UniqueActions убирает повторы из списка действий маршрута и паникует, если он пуст:
+// пустой список является ошибкой конфигурации и обнаруживается при регистрации маршрута.
+// Используется RequireAny и RequireAll всех адаптеров, caller указывается в тексте паники
+func UniqueActions(caller string, actions []string) []string {
+	if len(actions) == 0 {
+		panic("locatorars: " + caller + " requires at least one action")
+	}
+
+	seen := make(map[string]struct{}, len(actions))
+	unique := make([]string, 0, len(actions))
+	for _, action := range actions {
+		if _, ok := seen[action]; ok {
+			continue
+		}
+		seen[action] = struct{}{}
+		unique = append(unique, action)
+	}
+	return unique
+}
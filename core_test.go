@@ -0,0 +1,122 @@
+package locatorars_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+// newCombinedServer настраивает фейк с разрешенными, запрещенными, медленными и сбойными действиями
+func newCombinedServer(t *testing.T) *locatorarstest.Server {
+	s := locatorarstest.NewServer(t)
+	s.Allow("allowed", "reports")
+	s.Allow("allowed.too", "reports")
+	s.AddRule(locatorarstest.Rule{Action: "slow", Allowed: true, Latency: 5 * time.Second})
+	s.Fail("broken", http.StatusServiceUnavailable)
+	s.Deny("denied")
+	s.Deny("denied.too")
+	return s
+}
+
+func TestAuthorizeAnyAll(t *testing.T) {
+	s := newCombinedServer(t)
+
+	tests := []struct {
+		name           string
+		all            bool
+		actions        []string
+		allowOnFailure bool
+		want           locatorars.Outcome
+		wantMissing    []string
+	}{
+		{name: "any allowed", actions: []string{"denied", "allowed"}, want: locatorars.OutcomeAllowed},
+		{name: "any first allow wins over slow checks", actions: []string{"slow", "allowed"}, want: locatorars.OutcomeAllowed},
+		{name: "any denied", actions: []string{"denied", "denied.too"}, want: locatorars.OutcomeDenied, wantMissing: []string{"denied", "denied.too"}},
+		{name: "any denied and failed", actions: []string{"denied", "broken"}, want: locatorars.OutcomeError},
+		{name: "any denied and failed fails open", actions: []string{"denied", "broken"}, allowOnFailure: true, want: locatorars.OutcomeFailOpen},
+		{name: "any allowed despite failure", actions: []string{"broken", "allowed"}, want: locatorars.OutcomeAllowed},
+		{name: "any without actions", want: locatorars.OutcomeDenied},
+		{name: "all allowed", all: true, actions: []string{"allowed", "allowed.too"}, want: locatorars.OutcomeAllowed},
+		{name: "all first deny wins over slow checks", all: true, actions: []string{"slow", "denied"}, want: locatorars.OutcomeDenied, wantMissing: []string{"denied"}},
+		{name: "all denied despite failure", all: true, actions: []string{"broken", "denied"}, allowOnFailure: true, want: locatorars.OutcomeDenied, wantMissing: []string{"denied"}},
+		{name: "all allowed and failed", all: true, actions: []string{"allowed", "broken"}, want: locatorars.OutcomeError},
+		{name: "all allowed and failed fails open", all: true, actions: []string{"allowed", "broken"}, allowOnFailure: true, want: locatorars.OutcomeFailOpen},
+		{name: "all without actions", all: true, want: locatorars.OutcomeDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := s.Config()
+			config.AllowOnFailure = tt.allowOnFailure
+			m := locatorars.NewMiddleware(config)
+
+			startTime := time.Now()
+			var result locatorars.AuthResult
+			if tt.all {
+				result = m.AuthorizeAll(context.Background(), "reports", tt.actions...)
+			} else {
+				result = m.AuthorizeAny(context.Background(), "reports", tt.actions...)
+			}
+			if elapsed := time.Since(startTime); elapsed > time.Second {
+				t.Errorf("check took %v, want it to stop at the first decisive result", elapsed)
+			}
+
+			if result.Outcome != tt.want {
+				t.Fatalf("outcome %v, want %v (err: %v)", result.Outcome, tt.want, result.Err)
+			}
+			missing := slices.Clone(result.Missing)
+			slices.Sort(missing)
+			if !slices.Equal(missing, tt.wantMissing) {
+				t.Errorf("missing %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		m := locatorars.NewMiddleware(s.Config())
+		if result := m.AuthorizeAny(context.Background(), "", "allowed"); result.Outcome != locatorars.OutcomeUnauthorized {
+			t.Errorf("AuthorizeAny outcome %v, want unauthorized", result.Outcome)
+		}
+		if result := m.AuthorizeAll(context.Background(), "", "allowed"); result.Outcome != locatorars.OutcomeUnauthorized {
+			t.Errorf("AuthorizeAll outcome %v, want unauthorized", result.Outcome)
+		}
+	})
+}
+
+func TestRequireAllMissingBody(t *testing.T) {
+	s := newCombinedServer(t)
+	handler := s.Middleware().RequireAllHTTP("allowed", "denied", "denied.too")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called for a denied request")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+	req.Header.Set(locatorars.EntitlementsHeader, "reports")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	var body struct {
+		Error   string   `json:"error"`
+		Missing []string `json:"missing"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("malformed body %q: %v", rec.Body.String(), err)
+	}
+	// Проверка завершается при первом запрете, поэтому в ответе есть хотя бы одно запрещенное действие
+	if body.Error != "Access denied" || len(body.Missing) == 0 {
+		t.Fatalf("body %+v, want access denied with missing actions", body)
+	}
+	for _, action := range body.Missing {
+		if action != "denied" && action != "denied.too" {
+			t.Errorf("missing contains %q, which is allowed", action)
+		}
+	}
+}
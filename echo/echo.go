@@ -0,0 +1,87 @@
+// Package echo предоставляет middleware проверки прав доступа locator-ars
+// для фреймворка Echo. Поведение совпадает с RequireAction для gin:
+// те же заголовки, политика AllowOnFailure, статусы и JSON-тела ответов.
+package echo
+
+import (
+	"context"
+
+	echov4 "github.com/labstack/echo/v4"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+)
+
+// DecisionFromContext возвращает ответ сервиса, сохраненный middleware при разрешении доступа.
+// Возвращает false, если решения нет, например при разрешении по политике AllowOnFailure
+func DecisionFromContext(c echov4.Context) (*locatorars.AccessResponse, bool) {
+	decision, ok := c.Get(locatorars.DecisionContextKey).(*locatorars.AccessResponse)
+	return decision, ok && decision != nil
+}
+
+// RequireAction создает middleware, который требует указанное действие
+func RequireAction(m *locatorars.Middleware, action string, opts ...locatorars.RouteOption) echov4.MiddlewareFunc {
+	return middleware(func(c echov4.Context) locatorars.AuthResult {
+		return m.Authorize(requestContext(c), action, m.Entitlements(c.Request()), opts...)
+	})
+}
+
+// RequireAny создает middleware, который требует хотя бы одно из указанных действий.
+// Повторы убираются, пустой список вызывает панику при регистрации маршрута
+func RequireAny(m *locatorars.Middleware, actions ...string) echov4.MiddlewareFunc {
+	actions = locatorars.UniqueActions("RequireAny", actions)
+	return middleware(func(c echov4.Context) locatorars.AuthResult {
+		return m.AuthorizeAny(requestContext(c), m.Entitlements(c.Request()), actions...)
+	})
+}
+
+// RequireAll создает middleware, который требует все указанные действия.
+// Повторы убираются, пустой список вызывает панику при регистрации маршрута
+func RequireAll(m *locatorars.Middleware, actions ...string) echov4.MiddlewareFunc {
+	actions = locatorars.UniqueActions("RequireAll", actions)
+	return middleware(func(c echov4.Context) locatorars.AuthResult {
+		return m.AuthorizeAll(requestContext(c), m.Entitlements(c.Request()), actions...)
+	})
+}
+
+// RequireExpr создает middleware, который требует истинности логического выражения над действиями.
+// Синтаксическая ошибка выражения возвращается при создании middleware
+func RequireExpr(m *locatorars.Middleware, source string, opts ...locatorars.RouteOption) (echov4.MiddlewareFunc, error) {
+	expr, err := locatorars.ParseExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	return middleware(func(c echov4.Context) locatorars.AuthResult {
+		return m.AuthorizeExpr(requestContext(c), expr, m.Entitlements(c.Request()), opts...)
+	}), nil
+}
+
+// requestContext возвращает контекст запроса с данными для журнала аудита
+func requestContext(c echov4.Context) context.Context {
+	return locatorars.ContextWithRequestInfo(c.Request().Context(), locatorars.RequestInfo{
+		ClientIP:  c.RealIP(),
+		Route:     c.Path(),
+		Method:    c.Request().Method,
+		RequestID: c.Request().Header.Get(locatorars.RequestIDHeader),
+	})
+}
+
+// middleware применяет результат проверки authorize к запросу Echo
+func middleware(authorize func(c echov4.Context) locatorars.AuthResult) echov4.MiddlewareFunc {
+	return func(next echov4.HandlerFunc) echov4.HandlerFunc {
+		return func(c echov4.Context) error {
+			result := authorize(c)
+			switch {
+			case result.Allowed():
+				if result.Decision != nil {
+					c.Set(locatorars.DecisionContextKey, result.Decision)
+				}
+				return next(c)
+			case result.Outcome == locatorars.OutcomeCancelled:
+				// Клиент отменил запрос, отвечать некому
+				return nil
+			default:
+				return c.JSON(result.StatusCode(), result.Body())
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package echo_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	echov4 "github.com/labstack/echo/v4"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/conformance"
+	arsecho "github.com/LT-Devs/locator-ars-go-lib/echo"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(m *locatorars.Middleware, action string) http.Handler {
+		e := echov4.New()
+		e.GET("/", func(c echov4.Context) error {
+			decision, ok := arsecho.DecisionFromContext(c)
+			if !ok {
+				return c.String(http.StatusOK, "")
+			}
+			return c.String(http.StatusOK, fmt.Sprint(decision.User["name"]))
+		}, arsecho.RequireAction(m, action))
+		return e
+	})
+}
+
+func TestConformanceActions(t *testing.T) {
+	newHandler := func(require func(m *locatorars.Middleware, actions ...string) echov4.MiddlewareFunc) conformance.NewActionsHandler {
+		return func(m *locatorars.Middleware, actions ...string) http.Handler {
+			e := echov4.New()
+			e.GET("/", func(c echov4.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, require(m, actions...))
+			return e
+		}
+	}
+	conformance.RunActions(t, newHandler(arsecho.RequireAny), newHandler(arsecho.RequireAll))
+}
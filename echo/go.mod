@@ -0,0 +1,33 @@
+module github.com/LT-Devs/locator-ars-go-lib/echo
+
+go 1.24
+
+require (
+	github.com/LT-Devs/locator-ars-go-lib v0.2.0
+	github.com/labstack/echo/v4 v4.13.4
+)
+
+require (
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/labstack/gommon v0.4.2 // indirect
+	github.com/mattn/go-colorable v0.1.14 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/pelletier/go-toml/v2 v2.0.8 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasttemplate v1.2.2 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel v1.38.0 // indirect
+	go.opentelemetry.io/otel/metric v1.38.0 // indirect
+	go.opentelemetry.io/otel/trace v1.38.0 // indirect
+	golang.org/x/crypto v0.41.0 // indirect
+	golang.org/x/net v0.43.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// В рабочей копии репозитория модуль собирается с корневым модулем из соседнего каталога.
+// Зависимые модули replace не учитывают и получают версию из require,
+// поэтому корневой модуль тегируется раньше подмодулей (см. README, «Выпуск версий»)
+replace github.com/LT-Devs/locator-ars-go-lib => ../
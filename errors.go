@@ -0,0 +1,134 @@
+package locatorars
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBodySize сколько байт тела неуспешного ответа сохраняется в StatusError
+const maxErrorBodySize = 4096
+
+// Ошибки проверки доступа. Проверяются через errors.Is, так как возвращаемые
+// ошибки содержат подробности: исходную ошибку соединения, статус или действие
+var (
+	// ErrUnavailable сервис locator-ars недоступен: ошибка соединения, статус 5xx или 429,
+	// разомкнутый выключатель. Отмена запроса вызывающей стороной к ней не относится
+	ErrUnavailable = errors.New("access service is unavailable")
+
+	// ErrTimeout истек таймаут запроса к сервису или дедлайн контекста проверки
+	ErrTimeout = errors.New("access check timed out")
+
+	// ErrBadResponse ответ сервиса не удалось разобрать
+	ErrBadResponse = errors.New("access service returned a malformed response")
+
+	// ErrMissingEntitlements в запросе нет Entitlements
+	ErrMissingEntitlements = errors.New("missing entitlements")
+)
+
+// StatusError сервис locator-ars ответил неуспешным HTTP-статусом.
+// Для статусов 5xx и 429 errors.Is(err, ErrUnavailable) возвращает true
+type StatusError struct {
+	// HTTP-статус ответа
+	Code int
+
+	// Начало тела ответа (не более 4 КБ)
+	Body string
+
+	// retryAfter задержка из заголовка Retry-After
+	retryAfter time.Duration
+}
+
+// Error возвращает описание ошибки
+func (e *StatusError) Error() string {
+	return "access service returned non-200 status: " + strconv.Itoa(e.Code)
+}
+
+// Is сопоставляет статус с ErrUnavailable
+func (e *StatusError) Is(target error) bool {
+	return target == ErrUnavailable && (e.Code >= http.StatusInternalServerError || e.Code == http.StatusTooManyRequests)
+}
+
+// newStatusError формирует ошибку из неуспешного ответа сервиса
+func newStatusError(resp *http.Response) *StatusError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	return &StatusError{
+		Code:       resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// missingHeaderError в запросе нет заголовка, из которого HeaderExtractor извлекает Entitlements.
+// errors.Is(err, ErrMissingEntitlements) возвращает true
+type missingHeaderError struct {
+	header string
+}
+
+func (e *missingHeaderError) Error() string {
+	return "missing " + e.header + " header"
+}
+
+func (e *missingHeaderError) Is(target error) bool {
+	return target == ErrMissingEntitlements
+}
+
+// transportError ошибка установки соединения или чтения ответа
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// Is сопоставляет ошибку соединения с ErrUnavailable и ErrTimeout
+func (e *transportError) Is(target error) bool {
+	switch target {
+	case ErrUnavailable:
+		return !errors.Is(e.err, context.Canceled)
+	case ErrTimeout:
+		return isTimeout(e.err)
+	default:
+		return false
+	}
+}
+
+// isTimeout возвращает true для истекшего дедлайна контекста и таймаута HTTP-клиента
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isServiceFailure возвращает true, если сервис не смог ответить: он недоступен или истек таймаут.
+// Ответы 4xx, некорректные ответы и ошибки конфигурации к отказам сервиса не относятся
+func isServiceFailure(err error) bool {
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, ErrTimeout)
+}
+
+// failureOutcome определяет итог неудачной проверки: отмена или дедлайн входящего
+// запроса либо политика AllowOnFailure при отказе сервиса
+func failureOutcome(ctx context.Context, allowOnFailure bool) Outcome {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			return OutcomeTimeout
+		}
+		return OutcomeCancelled
+	}
+	if allowOnFailure {
+		return OutcomeFailOpen
+	}
+	return OutcomeError
+}
@@ -0,0 +1,190 @@
+package locatorars_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+)
+
+// errorsConfig возвращает конфигурацию клиента для сервиса по адресу url
+func errorsConfig(url string) locatorars.Config {
+	config := locatorars.DefaultConfig()
+	config.URL = url
+	config.LogLevel = locatorars.LogLevelNone
+	return config
+}
+
+// respond возвращает сервер, отвечающий статусом и телом
+func respond(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		// check выполняет проверку и возвращает ее ошибку
+		check           func(t *testing.T) error
+		wantUnavailable bool
+		wantTimeout     bool
+		wantBadResponse bool
+		wantCircuitOpen bool
+		// wantStatus код StatusError, 0 если ошибка не должна им быть
+		wantStatus int
+	}{
+		{
+			name: "transport",
+			check: func(t *testing.T) error {
+				server := respond(t, http.StatusOK, "")
+				server.Close()
+				_, err := locatorars.NewAccessClient(errorsConfig(server.URL)).CheckAccessDetailed(context.Background(), "reports.view", "reports")
+				return err
+			},
+			wantUnavailable: true,
+		},
+		{
+			name: "deadline",
+			check: func(t *testing.T) error {
+				release := make(chan struct{})
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					select {
+					case <-release:
+					case <-r.Context().Done():
+					}
+				}))
+				t.Cleanup(server.Close)
+				t.Cleanup(func() { close(release) })
+
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				defer cancel()
+				_, err := locatorars.NewAccessClient(errorsConfig(server.URL)).CheckAccessDetailed(ctx, "reports.view", "reports")
+				return err
+			},
+			wantUnavailable: true,
+			wantTimeout:     true,
+		},
+		{
+			name: "malformed JSON",
+			check: func(t *testing.T) error {
+				server := respond(t, http.StatusOK, `{"allowed":`)
+				_, err := locatorars.NewAccessClient(errorsConfig(server.URL)).CheckAccessDetailed(context.Background(), "reports.view", "reports")
+				return err
+			},
+			wantBadResponse: true,
+		},
+		{
+			name: "4xx",
+			check: func(t *testing.T) error {
+				server := respond(t, http.StatusBadRequest, "unknown action")
+				_, err := locatorars.NewAccessClient(errorsConfig(server.URL)).CheckAccessDetailed(context.Background(), "reports.view", "reports")
+				return err
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "5xx",
+			check: func(t *testing.T) error {
+				server := respond(t, http.StatusBadGateway, "upstream failed")
+				_, err := locatorars.NewAccessClient(errorsConfig(server.URL)).CheckAccessDetailed(context.Background(), "reports.view", "reports")
+				return err
+			},
+			wantUnavailable: true,
+			wantStatus:      http.StatusBadGateway,
+		},
+		{
+			name: "open breaker",
+			check: func(t *testing.T) error {
+				server := respond(t, http.StatusServiceUnavailable, "")
+				config := errorsConfig(server.URL)
+				config.CircuitBreaker.Enabled = true
+				config.CircuitBreaker.FailureThreshold = 1
+				config.CircuitBreaker.CoolDown = time.Minute
+				client := locatorars.NewAccessClient(config)
+
+				if _, err := client.CheckAccessDetailed(context.Background(), "reports.view", "reports"); err == nil {
+					t.Fatal("no error from a failing service")
+				}
+				_, err := client.CheckAccessDetailed(context.Background(), "reports.view", "reports")
+				return err
+			},
+			wantUnavailable: true,
+			wantCircuitOpen: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check(t)
+			if err == nil {
+				t.Fatal("no error")
+			}
+
+			for _, target := range []struct {
+				err  error
+				want bool
+			}{
+				{locatorars.ErrUnavailable, tt.wantUnavailable},
+				{locatorars.ErrTimeout, tt.wantTimeout},
+				{locatorars.ErrBadResponse, tt.wantBadResponse},
+				{locatorars.ErrCircuitOpen, tt.wantCircuitOpen},
+				{locatorars.ErrMissingEntitlements, false},
+			} {
+				if got := errors.Is(err, target.err); got != target.want {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", err, target.err, got, target.want)
+				}
+			}
+
+			var statusErr *locatorars.StatusError
+			if errors.As(err, &statusErr) != (tt.wantStatus != 0) {
+				t.Fatalf("errors.As(%v, *StatusError) = %v, want %v", err, statusErr != nil, tt.wantStatus != 0)
+			}
+			if statusErr != nil && statusErr.Code != tt.wantStatus {
+				t.Errorf("status %d, want %d", statusErr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestStatusErrorIs(t *testing.T) {
+	for code, want := range map[int]bool{
+		http.StatusBadRequest:          false,
+		http.StatusForbidden:           false,
+		http.StatusNotFound:            false,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusServiceUnavailable:  true,
+	} {
+		if got := errors.Is(&locatorars.StatusError{Code: code}, locatorars.ErrUnavailable); got != want {
+			t.Errorf("errors.Is(StatusError{%d}, ErrUnavailable) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestMissingEntitlementsError(t *testing.T) {
+	server := respond(t, http.StatusOK, `{"allowed":true}`)
+	config := errorsConfig(server.URL)
+
+	results := map[string]locatorars.AuthResult{
+		"access client": locatorars.NewAccessClient(config).Authorize(context.Background(), "reports.view", ""),
+		"middleware":    locatorars.NewMiddleware(config).Authorize(context.Background(), "reports.view", ""),
+		"expression": locatorars.NewMiddleware(config).AuthorizeExpr(context.Background(),
+			locatorars.MustParseExpr("reports.view || reports.export"), ""),
+	}
+	for name, result := range results {
+		if result.Outcome != locatorars.OutcomeUnauthorized || !errors.Is(result.Err, locatorars.ErrMissingEntitlements) {
+			t.Errorf("%s: outcome %v, err %v, want unauthorized with ErrMissingEntitlements", name, result.Outcome, result.Err)
+		}
+		if errors.Is(result.Err, locatorars.ErrUnavailable) {
+			t.Errorf("%s: missing entitlements match ErrUnavailable", name)
+		}
+	}
+}
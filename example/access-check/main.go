@@ -3,8 +3,9 @@ package main
 import (
 	"net/http"
 
-	"github.com/gin-gonic/gin"
 	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	arsgin "github.com/LT-Devs/locator-ars-go-lib/gin"
+	"github.com/gin-gonic/gin"
 )
 
 func main() {
@@ -17,16 +18,16 @@ func main() {
 	// Пример обработчика с условной проверкой прав доступа
 	r.GET("/conditionalreport", func(c *gin.Context) {
 		// Вариант 1: Проверка доступа извлекая данные из контекста
-		if arsMiddleware.CheckAccessFromContext(c, "viewdetailedreport") {
+		if arsgin.CheckAccessFromContext(arsMiddleware, c, "viewdetailedreport") {
 			// Пользователь имеет право на просмотр подробного отчета
 			c.JSON(http.StatusOK, gin.H{
-				"report": "Подробный отчет с секретными данными",
+				"report":       "Подробный отчет с секретными данными",
 				"access_level": "detailed",
 			})
 		} else {
 			// Пользователь не имеет права, показываем упрощенный отчет
 			c.JSON(http.StatusOK, gin.H{
-				"report": "Упрощенный отчет без секретных данных",
+				"report":       "Упрощенный отчет без секретных данных",
 				"access_level": "basic",
 			})
 		}
@@ -35,14 +36,23 @@ func main() {
 	// Пример с проверкой нескольких прав доступа
 	r.GET("/admin/dashboard", func(c *gin.Context) {
 		// Получаем заголовки вручную
-		jwt := c.GetHeader("X-Authentik-Jwt")
-		application := c.GetHeader("Application")
-		
-		// Проверяем разные права
-		canViewDashboard := arsMiddleware.CheckAccess("viewdashboard", jwt, application)
-		canManageUsers := arsMiddleware.CheckAccess("manageusers", jwt, application)
-		canExportData := arsMiddleware.CheckAccess("exportdata", jwt, application)
-		
+		entitlements := c.GetHeader("X-Authentik-Entitlements")
+		ctx := c.Request.Context()
+
+		// Проверяем разные права, прерывая проверки при отмене запроса клиентом
+		canViewDashboard := arsMiddleware.CheckAccessContext(ctx, "viewdashboard", entitlements)
+		canManageUsers := arsMiddleware.CheckAccessContext(ctx, "manageusers", entitlements)
+		canExportData := arsMiddleware.CheckAccessContext(ctx, "exportdata", entitlements)
+
+		// Формируем список доступных функций
+		features := []string{"base_dashboard"}
+		if canManageUsers {
+			features = append(features, "user_management")
+		}
+		if canExportData {
+			features = append(features, "data_export")
+		}
+
 		// Формируем ответ на основе проверки прав
 		c.JSON(http.StatusOK, gin.H{
 			"permissions": map[string]bool{
@@ -50,14 +60,10 @@ func main() {
 				"manage_users":   canManageUsers,
 				"export_data":    canExportData,
 			},
-			"features_available": []string{
-				"base_dashboard",
-				canManageUsers && "user_management",
-				canExportData && "data_export",
-			},
+			"features_available": features,
 		})
 	})
 
 	// Запуск сервера
 	r.Run(":8080")
-} 
\ No newline at end of file
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	arsgin "github.com/LT-Devs/locator-ars-go-lib/gin"
 )
 
 func main() {
@@ -19,7 +20,7 @@ func main() {
 	arsMiddleware := locatorars.NewMiddleware(config)
 
 	// Пример маршрута, защищенного проверкой прав доступа
-	r.GET("/admin", arsMiddleware.RequireAction("adminaccess"), func(c *gin.Context) {
+	r.GET("/admin", arsgin.RequireAction(arsMiddleware, "adminaccess"), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status": "admin access granted",
 		})
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	arsgin "github.com/LT-Devs/locator-ars-go-lib/gin"
 )
 
 // CustomLogger пример пользовательского логгера
@@ -71,7 +72,7 @@ func main() {
 	arsMiddleware := locatorars.NewMiddleware(config)
 
 	// Пример маршрута
-	r.GET("/reports", arsMiddleware.RequireAction("viewallreports"), func(c *gin.Context) {
+	r.GET("/reports", arsgin.RequireAction(arsMiddleware, "viewallreports"), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"reports": []string{"report1", "report2", "report3"},
 		})
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	arsgin "github.com/LT-Devs/locator-ars-go-lib/gin"
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+	// Читаем конфигурацию из файла, если он указан, иначе из переменных окружения
+	// LOCATOR_ARS_URL, LOCATOR_ARS_ALLOW_ON_FAILURE, LOCATOR_ARS_LOG_LEVEL, LOCATOR_ARS_TIMEOUT и т.д.
+	var (
+		config locatorars.Config
+		err    error
+	)
+	if path := os.Getenv("LOCATOR_ARS_CONFIG"); path != "" {
+		config, err = locatorars.ConfigFromFile(path)
+	} else {
+		config, err = locatorars.ConfigFromEnv("LOCATOR_ARS")
+	}
+	if err != nil {
+		log.Fatalf("Invalid locator-ars config: %v", err)
+	}
+
+	// Пишем журнал аудита решений в stdout в формате JSON Lines, не задерживая запросы
+	audit := locatorars.NewAsyncSink(locatorars.NewJSONLinesSink(os.Stdout), locatorars.AsyncSinkConfig{
+		BufferSize: 4096,
+		Overflow:   locatorars.OverflowDrop,
+	})
+	defer audit.Close()
+	config.AuditSink = audit
+
+	arsMiddleware := locatorars.NewMiddleware(config)
+
+	r := gin.Default()
+	r.GET("/admin", arsgin.RequireAction(arsMiddleware, "adminaccess"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "admin access granted",
+		})
+	})
+
+	if err := r.Run(":8080"); err != nil {
+		log.Fatal(err)
+	}
+}
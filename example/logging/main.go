@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	arsgin "github.com/LT-Devs/locator-ars-go-lib/gin"
 )
 
 func main() {
@@ -20,7 +21,7 @@ func main() {
 	arsMiddleware := locatorars.NewMiddleware(config)
 
 	// Пример использования middleware с логированием
-	r.GET("/reports", arsMiddleware.RequireAction("viewallreports"), func(c *gin.Context) {
+	r.GET("/reports", arsgin.RequireAction(arsMiddleware, "viewallreports"), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"reports": []string{"report1", "report2", "report3"},
 		})
@@ -29,7 +30,7 @@ func main() {
 	// Пример с проверкой доступа внутри обработчика
 	r.GET("/conditional", func(c *gin.Context) {
 		// При вызове CheckAccessFromContext будут записаны логи с информацией о проверке
-		if arsMiddleware.CheckAccessFromContext(c, "editreports") {
+		if arsgin.CheckAccessFromContext(arsMiddleware, c, "editreports") {
 			c.JSON(http.StatusOK, gin.H{
 				"can_edit": true,
 				"message": "You have edit permissions",
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	arsgin "github.com/LT-Devs/locator-ars-go-lib/gin"
 )
 
 func main() {
@@ -15,14 +16,14 @@ func main() {
 	arsMiddleware := locatorars.NewMiddleware(locatorars.DefaultConfig())
 
 	// Пример маршрута, защищенного проверкой прав доступа
-	r.GET("/reports", arsMiddleware.RequireAction("viewallreports"), func(c *gin.Context) {
+	r.GET("/reports", arsgin.RequireAction(arsMiddleware, "viewallreports"), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"reports": []string{"report1", "report2", "report3"},
 		})
 	})
 
 	// Пример с другим действием
-	r.POST("/reports", arsMiddleware.RequireAction("createreport"), func(c *gin.Context) {
+	r.POST("/reports", arsgin.RequireAction(arsMiddleware, "createreport"), func(c *gin.Context) {
 		c.JSON(http.StatusCreated, gin.H{
 			"message": "Report created",
 		})
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+)
+
+func main() {
+	// Создаем middleware с конфигурацией по умолчанию
+	arsMiddleware := locatorars.NewMiddleware(locatorars.DefaultConfig())
+
+	mux := http.NewServeMux()
+
+	// Пример маршрута, защищенного проверкой прав доступа
+	reports := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Получаем ответ сервиса с данными пользователя
+		var user map[string]interface{}
+		if decision, ok := locatorars.DecisionFromRequest(r); ok {
+			user = decision.User
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"reports": []string{"report1", "report2", "report3"},
+			"user":    user,
+		})
+	})
+	mux.Handle("/reports", arsMiddleware.RequireActionHTTP("viewallreports")(reports))
+
+	// Пример маршрута, доступного при наличии любого из прав
+	dashboard := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("dashboard"))
+	})
+	mux.Handle("/dashboard", arsMiddleware.RequireAnyHTTP("viewdashboard", "admin")(dashboard))
+
+	// Запуск сервера
+	log.Fatal(http.ListenAndServe(":8080", mux))
+}
@@ -1,21 +1,34 @@
 package main
 
 import (
+	"log"
 	"net/http"
 
-	"github.com/gin-gonic/gin"
 	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	// Создаем экземпляр gin
 	r := gin.Default()
 
+	// Проверяем JWT локально по ключам Authentik, чтобы не доверять
+	// заголовку с Entitlements при обращении в обход прокси
+	jwtExtractor, err := locatorars.NewJWTExtractor(locatorars.JWTConfig{
+		JWKSURL: "http://authentik/application/o/locator/jwks/",
+		Issuer:  "http://authentik/application/o/locator/",
+	})
+	if err != nil {
+		log.Fatalf("Failed to load JWKS: %v", err)
+	}
+	defer jwtExtractor.Close()
+
 	// Создаем middleware с конфигурацией и логированием
 	config := locatorars.Config{
-		URL:            "http://locator/api/v1/ars/check",
-		AllowOnFailure: false,
-		LogLevel:       locatorars.LogLevelDebug, // Включаем подробное логирование
+		URL:                  "http://locator/api/v1/ars/check",
+		AllowOnFailure:       false,
+		LogLevel:             locatorars.LogLevelDebug, // Включаем подробное логирование
+		EntitlementExtractor: jwtExtractor,
 	}
 	arsMiddleware := locatorars.NewMiddleware(config)
 
@@ -30,17 +43,17 @@ func main() {
 			return
 		}
 
-		// Получаем JWT из заголовка
-		jwt := c.GetHeader("X-Authentik-Jwt")
-		if jwt == "" {
+		// Получаем Entitlements из проверенного JWT
+		entitlements := arsMiddleware.Entitlements(c.Request)
+		if entitlements == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "X-Authentik-Jwt header is required",
+				"error": "Valid X-Authentik-Jwt header is required",
 			})
 			return
 		}
 
 		// Проверяем доступ для указанного приложения
-		allowed := arsMiddleware.CheckAccess("view", jwt, application)
+		allowed := arsMiddleware.CheckAccessContext(c.Request.Context(), "view", entitlements)
 
 		// Выводим результат проверки
 		c.JSON(http.StatusOK, gin.H{
@@ -52,4 +65,4 @@ func main() {
 
 	// Запуск сервера
 	r.Run(":8080")
-} 
\ No newline at end of file
+}
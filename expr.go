@@ -0,0 +1,354 @@
+package locatorars
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Expr разобранное логическое выражение над действиями, например
+// "(reports.view && region.eu) || admin".
+//
+// Поддерживаются операторы || (ИЛИ), && (И), ! (НЕ) и круглые скобки.
+// Приоритет операторов: !, затем &&, затем ||. Имена действий могут содержать
+// буквы, цифры и символы . _ - : /
+type Expr struct {
+	source  string
+	root    exprNode
+	actions []string
+}
+
+// ParseExpr разбирает выражение и возвращает ошибку с позицией при синтаксической ошибке
+func ParseExpr(source string) (*Expr, error) {
+	p := &exprParser{source: source}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 1 {
+		return nil, fmt.Errorf("invalid expression %q: expression is empty", source)
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorAt(tok, "unexpected %s", tok)
+	}
+
+	seen := make(map[string]struct{})
+	var actions []string
+	collectActions(root, seen, &actions)
+
+	return &Expr{source: source, root: root, actions: actions}, nil
+}
+
+// MustParseExpr разбирает выражение и паникует при ошибке.
+// Предназначен для выражений, заданных в коде
+func MustParseExpr(source string) *Expr {
+	expr, err := ParseExpr(source)
+	if err != nil {
+		panic("locatorars: " + err.Error())
+	}
+	return expr
+}
+
+// String возвращает исходный текст выражения
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Actions возвращает уникальные действия, упомянутые в выражении, в порядке появления
+func (e *Expr) Actions() []string {
+	return append([]string(nil), e.actions...)
+}
+
+// Evaluate вычисляет выражение, используя check для проверки отдельных действий.
+// Вычисление ленивое: правая часть && и || проверяется, только если она влияет на результат.
+// Каждое действие проверяется не более одного раза. Первая ошибка check прерывает вычисление
+func (e *Expr) Evaluate(ctx context.Context, check func(ctx context.Context, action string) (bool, error)) (bool, error) {
+	ev := &exprEvaluator{
+		ctx:     ctx,
+		check:   check,
+		results: make(map[string]bool, len(e.actions)),
+	}
+	return e.root.eval(ev)
+}
+
+// CheckExpr проверяет права доступа по логическому выражению над действиями
+func (ac *AccessClient) CheckExpr(ctx context.Context, expr *Expr, entitlements string) (bool, error) {
+	allowed, _, err := checkExpr(ctx, ac, expr, entitlements)
+	return allowed, err
+}
+
+// checkExpr вычисляет выражение, проверяя действия через checker
+// и возвращает время получения самого старого из использованных решений
+func checkExpr(ctx context.Context, checker AccessChecker, expr *Expr, entitlements string) (allowed bool, confirmedAt time.Time, err error) {
+	// Ошибка прерывает вычисление: политика AllowOnFailure применяется
+	// ко всему выражению, а не к отдельному действию
+	allowed, err = expr.Evaluate(ctx, func(ctx context.Context, action string) (bool, error) {
+		decision, err := checker.CheckAccessDetailed(ctx, action, entitlements)
+		if err != nil {
+			return false, err
+		}
+		if at := decision.confirmed(); confirmedAt.IsZero() || at.Before(confirmedAt) {
+			confirmedAt = at
+		}
+		return decision.Allowed, nil
+	})
+	return allowed, confirmedAt, err
+}
+
+// exprEvaluator состояние одного вычисления выражения
+type exprEvaluator struct {
+	ctx     context.Context
+	check   func(ctx context.Context, action string) (bool, error)
+	results map[string]bool
+}
+
+// exprNode узел дерева выражения
+type exprNode interface {
+	eval(ev *exprEvaluator) (bool, error)
+}
+
+type actionNode struct {
+	action string
+}
+
+func (n *actionNode) eval(ev *exprEvaluator) (bool, error) {
+	if allowed, ok := ev.results[n.action]; ok {
+		return allowed, nil
+	}
+	if err := ev.ctx.Err(); err != nil {
+		return false, err
+	}
+
+	allowed, err := ev.check(ev.ctx, n.action)
+	if err != nil {
+		return false, fmt.Errorf("action %s: %w", n.action, err)
+	}
+	ev.results[n.action] = allowed
+	return allowed, nil
+}
+
+type notNode struct {
+	operand exprNode
+}
+
+func (n *notNode) eval(ev *exprEvaluator) (bool, error) {
+	value, err := n.operand.eval(ev)
+	if err != nil {
+		return false, err
+	}
+	return !value, nil
+}
+
+type andNode struct {
+	left, right exprNode
+}
+
+func (n *andNode) eval(ev *exprEvaluator) (bool, error) {
+	left, err := n.left.eval(ev)
+	if err != nil || !left {
+		return false, err
+	}
+	return n.right.eval(ev)
+}
+
+type orNode struct {
+	left, right exprNode
+}
+
+func (n *orNode) eval(ev *exprEvaluator) (bool, error) {
+	left, err := n.left.eval(ev)
+	if err != nil || left {
+		return left, err
+	}
+	return n.right.eval(ev)
+}
+
+// collectActions собирает уникальные действия дерева в порядке обхода
+func collectActions(node exprNode, seen map[string]struct{}, actions *[]string) {
+	switch n := node.(type) {
+	case *actionNode:
+		if _, ok := seen[n.action]; !ok {
+			seen[n.action] = struct{}{}
+			*actions = append(*actions, n.action)
+		}
+	case *notNode:
+		collectActions(n.operand, seen, actions)
+	case *andNode:
+		collectActions(n.left, seen, actions)
+		collectActions(n.right, seen, actions)
+	case *orNode:
+		collectActions(n.left, seen, actions)
+		collectActions(n.right, seen, actions)
+	}
+}
+
+// tokenKind тип лексемы выражения
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenAction
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+// exprToken лексема выражения с позицией (с 1) в исходной строке
+type exprToken struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t exprToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenAction:
+		return fmt.Sprintf("action %q", t.value)
+	default:
+		return fmt.Sprintf("%q", t.value)
+	}
+}
+
+// exprParser рекурсивный нисходящий разборщик выражений
+type exprParser struct {
+	source string
+	tokens []exprToken
+	next   int
+}
+
+// tokenize разбивает исходную строку на лексемы
+func (p *exprParser) tokenize() error {
+	src := p.source
+	for i := 0; i < len(src); {
+		ch := src[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(':
+			p.tokens = append(p.tokens, exprToken{kind: tokenLParen, value: "(", pos: i + 1})
+			i++
+		case ch == ')':
+			p.tokens = append(p.tokens, exprToken{kind: tokenRParen, value: ")", pos: i + 1})
+			i++
+		case ch == '!':
+			p.tokens = append(p.tokens, exprToken{kind: tokenNot, value: "!", pos: i + 1})
+			i++
+		case strings.HasPrefix(src[i:], "&&"):
+			p.tokens = append(p.tokens, exprToken{kind: tokenAnd, value: "&&", pos: i + 1})
+			i += 2
+		case strings.HasPrefix(src[i:], "||"):
+			p.tokens = append(p.tokens, exprToken{kind: tokenOr, value: "||", pos: i + 1})
+			i += 2
+		case isActionChar(ch):
+			start := i
+			for i < len(src) && isActionChar(src[i]) {
+				i++
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokenAction, value: src[start:i], pos: start + 1})
+		default:
+			return fmt.Errorf("invalid expression %q at position %d: unexpected character %q", src, i+1, ch)
+		}
+	}
+	p.tokens = append(p.tokens, exprToken{kind: tokenEOF, pos: len(src) + 1})
+	return nil
+}
+
+// isActionChar проверяет, может ли символ входить в имя действия
+func isActionChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' ||
+		ch >= 'A' && ch <= 'Z' ||
+		ch >= '0' && ch <= '9' ||
+		ch == '.' || ch == '_' || ch == '-' || ch == ':' || ch == '/'
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.next]
+}
+
+func (p *exprParser) advance() exprToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *exprParser) errorAt(tok exprToken, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid expression %q at position %d: %s", p.source, tok.pos, fmt.Sprintf(format, args...))
+}
+
+// parseOr разбирает: and ("||" and)*
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd разбирает: unary ("&&" unary)*
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenAnd {
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseUnary разбирает: "!" unary | primary
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek().kind == tokenNot {
+		p.advance()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary разбирает: action | "(" or ")"
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case tokenAction:
+		return &actionNode{action: tok.value}, nil
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenRParen {
+			return nil, p.errorAt(closing, "expected \")\", got %s", closing)
+		}
+		return node, nil
+	default:
+		return nil, p.errorAt(tok, "expected action or \"(\", got %s", tok)
+	}
+}
@@ -0,0 +1,211 @@
+package locatorars
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// exprChecker проверяет действия по карте разрешений и запоминает порядок проверок.
+// Действие "broken" завершается ошибкой недоступности сервиса
+type exprChecker struct {
+	allowed map[string]bool
+	checked []string
+}
+
+func (c *exprChecker) check(ctx context.Context, action string) (bool, error) {
+	c.checked = append(c.checked, action)
+	if action == "broken" {
+		return false, &StatusError{Code: http.StatusServiceUnavailable}
+	}
+	return c.allowed[action], nil
+}
+
+func TestParseExpr(t *testing.T) {
+	allowed := map[string]bool{"a": true, "b": false, "c": true, "reports.view": true, "region:eu/west": true}
+
+	tests := []struct {
+		source      string
+		want        bool
+		wantActions []string
+	}{
+		{source: "a", want: true, wantActions: []string{"a"}},
+		{source: "!b", want: true, wantActions: []string{"b"}},
+		{source: "!!a", want: true, wantActions: []string{"a"}},
+		{source: "a && b", want: false, wantActions: []string{"a", "b"}},
+		{source: "b || a", want: true, wantActions: []string{"b", "a"}},
+		// && связывает сильнее ||: b && a || c == (b && a) || c
+		{source: "b && a || c", want: true, wantActions: []string{"b", "a", "c"}},
+		{source: "c || a && b", want: true, wantActions: []string{"c", "a", "b"}},
+		{source: "(c || a) && b", want: false, wantActions: []string{"c", "a", "b"}},
+		// ! связывает сильнее &&: !b && a == (!b) && a
+		{source: "!b && a", want: true, wantActions: []string{"b", "a"}},
+		{source: "!(a && c)", want: false, wantActions: []string{"a", "c"}},
+		{source: "!a || !b", want: true, wantActions: []string{"a", "b"}},
+		{source: " ( ( a ) ) ", want: true, wantActions: []string{"a"}},
+		{source: "a && a || a", want: true, wantActions: []string{"a"}},
+		{source: "reports.view && region:eu/west", want: true, wantActions: []string{"reports.view", "region:eu/west"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := ParseExpr(tt.source)
+			if err != nil {
+				t.Fatalf("ParseExpr: %v", err)
+			}
+			if expr.String() != tt.source {
+				t.Errorf("String() %q, want %q", expr.String(), tt.source)
+			}
+			if !slices.Equal(expr.Actions(), tt.wantActions) {
+				t.Errorf("actions %v, want %v", expr.Actions(), tt.wantActions)
+			}
+
+			checker := &exprChecker{allowed: allowed}
+			got, err := expr.Evaluate(context.Background(), checker.check)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr string
+	}{
+		{source: "", wantErr: "expression is empty"},
+		{source: "   ", wantErr: "expression is empty"},
+		{source: "a b", wantErr: `position 3: unexpected action "b"`},
+		{source: "(a", wantErr: `position 3: expected ")", got end of expression`},
+		{source: "a)", wantErr: `position 2: unexpected ")"`},
+		{source: "!", wantErr: "position 2: expected action or \"(\", got end of expression"},
+		{source: "a &&", wantErr: "position 5: expected action or \"(\", got end of expression"},
+		{source: "|| a", wantErr: `position 1: expected action or "(", got "||"`},
+		{source: "()", wantErr: `position 2: expected action or "(", got ")"`},
+		{source: "a & b", wantErr: `position 3: unexpected character '&'`},
+		{source: "a == b", wantErr: `position 3: unexpected character '='`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := ParseExpr(tt.source)
+			if err == nil {
+				t.Fatal("ParseExpr accepted an invalid expression")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustParseExpr did not panic on an invalid expression")
+		}
+	}()
+	MustParseExpr("a b")
+}
+
+func TestExprEvaluate(t *testing.T) {
+	allowed := map[string]bool{"a": true, "b": false}
+
+	tests := []struct {
+		source      string
+		want        bool
+		wantErr     bool
+		wantChecked []string
+	}{
+		// Правая часть не проверяется, если результат уже известен
+		{source: "b && broken", want: false, wantChecked: []string{"b"}},
+		{source: "a || broken", want: true, wantChecked: []string{"a"}},
+		{source: "!a && broken", want: false, wantChecked: []string{"a"}},
+		// Повторное действие проверяется один раз
+		{source: "(a && b) || a", want: true, wantChecked: []string{"a", "b"}},
+		// Ошибка прерывает вычисление, в том числе под отрицанием
+		{source: "broken || a", wantErr: true, wantChecked: []string{"broken"}},
+		{source: "!broken", wantErr: true, wantChecked: []string{"broken"}},
+		{source: "!broken || a", wantErr: true, wantChecked: []string{"broken"}},
+		{source: "a && !broken", wantErr: true, wantChecked: []string{"a", "broken"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			checker := &exprChecker{allowed: allowed}
+			got, err := MustParseExpr(tt.source).Evaluate(context.Background(), checker.check)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnavailable) {
+					t.Fatalf("err %v, want ErrUnavailable", err)
+				}
+				if got {
+					t.Error("failed evaluation returned true")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Evaluate: %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("Evaluate %v, want %v", got, tt.want)
+				}
+			}
+			if !slices.Equal(checker.checked, tt.wantChecked) {
+				t.Errorf("checked %v, want %v", checker.checked, tt.wantChecked)
+			}
+		})
+	}
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		checker := &exprChecker{allowed: allowed}
+		if _, err := MustParseExpr("a").Evaluate(ctx, checker.check); !errors.Is(err, context.Canceled) {
+			t.Errorf("err %v, want context.Canceled", err)
+		}
+		if len(checker.checked) != 0 {
+			t.Errorf("checked %v after cancellation", checker.checked)
+		}
+	})
+}
+
+func TestAuthorizeExpr(t *testing.T) {
+	checker := &exprChecker{allowed: map[string]bool{"reports.view": true, "admin": false}}
+	fake := CheckerFunc(func(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+		allowed, err := checker.check(ctx, action)
+		if err != nil {
+			return nil, err
+		}
+		return &AccessResponse{Action: action, Allowed: allowed}, nil
+	})
+
+	tests := []struct {
+		source         string
+		entitlements   string
+		allowOnFailure bool
+		opts           []RouteOption
+		want           Outcome
+	}{
+		{source: "reports.view && !admin", entitlements: "reports", want: OutcomeAllowed},
+		{source: "admin || !reports.view", entitlements: "reports", want: OutcomeDenied},
+		{source: "reports.view", want: OutcomeUnauthorized},
+		{source: "!broken", entitlements: "reports", want: OutcomeError},
+		{source: "!broken", entitlements: "reports", allowOnFailure: true, want: OutcomeFailOpen},
+		{source: "!broken", entitlements: "reports", allowOnFailure: true, opts: []RouteOption{FailClosed()}, want: OutcomeError},
+		{source: "!broken", entitlements: "reports", opts: []RouteOption{FailOpen()}, want: OutcomeFailOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			config := DefaultConfig()
+			config.LogLevel = LogLevelNone
+			config.AllowOnFailure = tt.allowOnFailure
+			m := NewMiddlewareWithChecker(fake, config)
+
+			result := m.AuthorizeExpr(context.Background(), MustParseExpr(tt.source), tt.entitlements, tt.opts...)
+			if result.Outcome != tt.want {
+				t.Fatalf("outcome %v, want %v (err: %v)", result.Outcome, tt.want, result.Err)
+			}
+		})
+	}
+}
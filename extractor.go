@@ -0,0 +1,184 @@
+package locatorars
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultJWTHeader заголовок, в котором Authentik передает JWT пользователя
+const DefaultJWTHeader = "X-Authentik-Jwt"
+
+// entitlementsSeparator разделитель Entitlements при объединении списка в строку,
+// как в заголовке X-Authentik-Entitlements
+const entitlementsSeparator = "|"
+
+// EntitlementExtractor извлекает Entitlements из входящего запроса.
+// Пустая строка без ошибки означает, что Entitlements в запросе нет
+type EntitlementExtractor interface {
+	Extract(r *http.Request) (string, error)
+}
+
+// ExtractorFunc позволяет использовать функцию как EntitlementExtractor
+type ExtractorFunc func(r *http.Request) (string, error)
+
+// Extract вызывает f(r)
+func (f ExtractorFunc) Extract(r *http.Request) (string, error) {
+	return f(r)
+}
+
+// HeaderExtractor извлекает Entitlements из заголовка name.
+// При отсутствии заголовка ответ 401 называет его: "Missing <name> header"
+func HeaderExtractor(name string) EntitlementExtractor {
+	return headerExtractor(name)
+}
+
+// headerExtractor извлекает Entitlements из заголовка с этим именем
+type headerExtractor string
+
+// Extract возвращает значение заголовка
+func (h headerExtractor) Extract(r *http.Request) (string, error) {
+	return r.Header.Get(string(h)), nil
+}
+
+// CookieExtractor извлекает Entitlements из cookie name
+func CookieExtractor(name string) EntitlementExtractor {
+	return ExtractorFunc(func(r *http.Request) (string, error) {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			// Отсутствие cookie не является ошибкой
+			return "", nil
+		}
+		return cookie.Value, nil
+	})
+}
+
+// QueryExtractor извлекает Entitlements из параметра запроса name
+func QueryExtractor(name string) EntitlementExtractor {
+	return ExtractorFunc(func(r *http.Request) (string, error) {
+		return r.URL.Query().Get(name), nil
+	})
+}
+
+// ContextKeyExtractor извлекает Entitlements, сохраненные в контексте запроса
+// предыдущим middleware. Значение может быть строкой или списком строк.
+// В адаптере gin также доступны значения, сохраненные через c.Set со строковым ключом
+func ContextKeyExtractor(key interface{}) EntitlementExtractor {
+	return ExtractorFunc(func(r *http.Request) (string, error) {
+		return entitlementsFromValue(r.Context().Value(key))
+	})
+}
+
+// JWTClaimExtractor извлекает Entitlements из claim JWT в заголовке header
+// (по умолчанию X-Authentik-Jwt). Вложенные claim указываются через точку,
+// например "ak_proxy.entitlements". Список значений объединяется через "|".
+//
+// Подпись токена НЕ проверяется: используйте этот вариант только за прокси,
+// которому вы доверяете, иначе используйте NewJWTExtractor с проверкой подписи
+func JWTClaimExtractor(header, claim string) EntitlementExtractor {
+	if header == "" {
+		header = DefaultJWTHeader
+	}
+	return ExtractorFunc(func(r *http.Request) (string, error) {
+		token := bearerToken(r.Header.Get(header))
+		if token == "" {
+			return "", nil
+		}
+
+		parts := strings.Split(token, ".")
+		if len(parts) != 3 {
+			return "", fmt.Errorf("malformed JWT: expected 3 segments, got %d", len(parts))
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return "", fmt.Errorf("malformed JWT payload: %w", err)
+		}
+
+		var claims map[string]interface{}
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			return "", fmt.Errorf("malformed JWT claims: %w", err)
+		}
+		return claimEntitlements(claims, claim)
+	})
+}
+
+// ChainExtractor опрашивает извлекатели по порядку и возвращает первое непустое значение.
+// Ошибка извлекателя прерывает опрос
+func ChainExtractor(extractors ...EntitlementExtractor) EntitlementExtractor {
+	return ExtractorFunc(func(r *http.Request) (string, error) {
+		for _, extractor := range extractors {
+			entitlements, err := extractor.Extract(r)
+			if err != nil {
+				return "", err
+			}
+			if entitlements != "" {
+				return entitlements, nil
+			}
+		}
+		return "", nil
+	})
+}
+
+// Entitlements извлекает Entitlements из запроса настроенным EntitlementExtractor.
+// Ошибка извлечения логируется на уровне Error и приводит к пустому результату (ответ 401)
+func (m *Middleware) Entitlements(r *http.Request) string {
+	return m.snapshot().entitlements(r)
+}
+
+// entitlements реализует Entitlements на зафиксированной конфигурации
+func (m *Middleware) entitlements(r *http.Request) string {
+	entitlements, err := m.extractor.Extract(r)
+	if err != nil {
+		m.logger.Error("Failed to extract entitlements from request: %v", err)
+		return ""
+	}
+	return entitlements
+}
+
+// bearerToken убирает необязательный префикс "Bearer " из значения заголовка
+func bearerToken(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) > 7 && strings.EqualFold(value[:7], "bearer ") {
+		return strings.TrimSpace(value[7:])
+	}
+	return value
+}
+
+// claimEntitlements извлекает Entitlements из claim по пути через точку
+func claimEntitlements(claims map[string]interface{}, path string) (string, error) {
+	var value interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		value = object[name]
+	}
+	return entitlementsFromValue(value)
+}
+
+// entitlementsFromValue приводит значение claim или контекста к строке Entitlements
+func entitlementsFromValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []string:
+		return strings.Join(v, entitlementsSeparator), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", fmt.Errorf("unexpected entitlement value of type %T", item)
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, entitlementsSeparator), nil
+	default:
+		return "", fmt.Errorf("unexpected entitlements value of type %T", value)
+	}
+}
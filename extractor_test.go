@@ -0,0 +1,283 @@
+package locatorars
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// contextKey ключ контекста для проверки ContextKeyExtractor
+type contextKey struct{}
+
+// unsignedToken выпускает токен без подписи: JWTClaimExtractor подпись не проверяет
+func unsignedToken(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(payload) + "."
+}
+
+func TestExtractors(t *testing.T) {
+	token := unsignedToken(t, map[string]interface{}{
+		"entitlements": []string{"reports", "billing"},
+		"ak_proxy":     map[string]interface{}{"entitlements": "nested"},
+		"mixed":        []interface{}{"reports", 1},
+	})
+
+	tests := []struct {
+		name      string
+		extractor EntitlementExtractor
+		prepare   func(r *http.Request) *http.Request
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "header",
+			extractor: HeaderExtractor("X-Entitlements"),
+			prepare: func(r *http.Request) *http.Request {
+				r.Header.Set("X-Entitlements", "reports")
+				return r
+			},
+			want: "reports",
+		},
+		{name: "header missing", extractor: HeaderExtractor("X-Entitlements")},
+		{
+			name:      "cookie",
+			extractor: CookieExtractor("ents"),
+			prepare: func(r *http.Request) *http.Request {
+				r.AddCookie(&http.Cookie{Name: "ents", Value: "reports"})
+				return r
+			},
+			want: "reports",
+		},
+		{name: "cookie missing", extractor: CookieExtractor("ents")},
+		{
+			name:      "query",
+			extractor: QueryExtractor("ents"),
+			prepare: func(r *http.Request) *http.Request {
+				r.URL.RawQuery = "ents=reports%7Cbilling"
+				return r
+			},
+			want: "reports|billing",
+		},
+		{name: "query missing", extractor: QueryExtractor("ents")},
+		{
+			name:      "context string",
+			extractor: ContextKeyExtractor(contextKey{}),
+			prepare: func(r *http.Request) *http.Request {
+				return r.WithContext(context.WithValue(r.Context(), contextKey{}, "reports"))
+			},
+			want: "reports",
+		},
+		{
+			name:      "context list",
+			extractor: ContextKeyExtractor(contextKey{}),
+			prepare: func(r *http.Request) *http.Request {
+				return r.WithContext(context.WithValue(r.Context(), contextKey{}, []string{"reports", "billing"}))
+			},
+			want: "reports|billing",
+		},
+		{
+			name:      "context unexpected type",
+			extractor: ContextKeyExtractor(contextKey{}),
+			prepare: func(r *http.Request) *http.Request {
+				return r.WithContext(context.WithValue(r.Context(), contextKey{}, 42))
+			},
+			wantErr: true,
+		},
+		{name: "context missing", extractor: ContextKeyExtractor(contextKey{})},
+		{
+			name:      "jwt claim",
+			extractor: JWTClaimExtractor("", "entitlements"),
+			prepare: func(r *http.Request) *http.Request {
+				r.Header.Set(DefaultJWTHeader, "Bearer "+token)
+				return r
+			},
+			want: "reports|billing",
+		},
+		{
+			name:      "jwt nested claim",
+			extractor: JWTClaimExtractor("X-Token", "ak_proxy.entitlements"),
+			prepare: func(r *http.Request) *http.Request {
+				r.Header.Set("X-Token", token)
+				return r
+			},
+			want: "nested",
+		},
+		{
+			name:      "jwt missing claim",
+			extractor: JWTClaimExtractor("", "ak_proxy.missing.entitlements"),
+			prepare: func(r *http.Request) *http.Request {
+				r.Header.Set(DefaultJWTHeader, token)
+				return r
+			},
+		},
+		{
+			name:      "jwt claim with non-string item",
+			extractor: JWTClaimExtractor("", "mixed"),
+			prepare: func(r *http.Request) *http.Request {
+				r.Header.Set(DefaultJWTHeader, token)
+				return r
+			},
+			wantErr: true,
+		},
+		{
+			name:      "jwt malformed",
+			extractor: JWTClaimExtractor("", "entitlements"),
+			prepare: func(r *http.Request) *http.Request {
+				r.Header.Set(DefaultJWTHeader, "Bearer not-a-token")
+				return r
+			},
+			wantErr: true,
+		},
+		{
+			name:      "jwt malformed payload",
+			extractor: JWTClaimExtractor("", "entitlements"),
+			prepare: func(r *http.Request) *http.Request {
+				r.Header.Set(DefaultJWTHeader, "e30.not+base64.")
+				return r
+			},
+			wantErr: true,
+		},
+		{name: "jwt missing", extractor: JWTClaimExtractor("", "entitlements")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+			if tt.prepare != nil {
+				req = tt.prepare(req)
+			}
+
+			got, err := tt.extractor.Extract(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("entitlements %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainExtractor(t *testing.T) {
+	var called []string
+	source := func(name, value string, err error) EntitlementExtractor {
+		return ExtractorFunc(func(r *http.Request) (string, error) {
+			called = append(called, name)
+			return value, err
+		})
+	}
+	failure := errors.New("extractor failed")
+
+	tests := []struct {
+		name       string
+		extractors []EntitlementExtractor
+		want       string
+		wantErr    error
+		wantCalled []string
+	}{
+		{
+			name:       "first non-empty wins",
+			extractors: []EntitlementExtractor{source("a", "", nil), source("b", "from b", nil), source("c", "from c", nil)},
+			want:       "from b",
+			wantCalled: []string{"a", "b"},
+		},
+		{
+			name:       "all empty",
+			extractors: []EntitlementExtractor{source("a", "", nil), source("b", "", nil)},
+			wantCalled: []string{"a", "b"},
+		},
+		{
+			name:       "error stops the chain",
+			extractors: []EntitlementExtractor{source("a", "", failure), source("b", "from b", nil)},
+			wantErr:    failure,
+			wantCalled: []string{"a"},
+		},
+		{name: "empty chain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = nil
+			got, err := ChainExtractor(tt.extractors...).Extract(httptest.NewRequest(http.MethodGet, "/", nil))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("entitlements %q, want %q", got, tt.want)
+			}
+			if len(called) != len(tt.wantCalled) {
+				t.Fatalf("called %v, want %v", called, tt.wantCalled)
+			}
+			for i := range called {
+				if called[i] != tt.wantCalled[i] {
+					t.Errorf("called %v, want %v", called, tt.wantCalled)
+				}
+			}
+		})
+	}
+
+	t.Run("header, then cookie", func(t *testing.T) {
+		chain := ChainExtractor(HeaderExtractor(EntitlementsHeader), CookieExtractor("ents"))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "ents", Value: "from cookie"})
+		if got, _ := chain.Extract(req); got != "from cookie" {
+			t.Errorf("entitlements %q, want the cookie value", got)
+		}
+		req.Header.Set(EntitlementsHeader, "from header")
+		if got, _ := chain.Extract(req); got != "from header" {
+			t.Errorf("entitlements %q, want the header value", got)
+		}
+	})
+}
+
+func TestMiddlewareEntitlements(t *testing.T) {
+	fake := CheckerFunc(func(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+		return &AccessResponse{Action: action, Allowed: true}, nil
+	})
+
+	tests := []struct {
+		name      string
+		extractor EntitlementExtractor
+		wantError string
+	}{
+		{name: "default header", wantError: "Missing X-Authentik-Entitlements header"},
+		{name: "custom header", extractor: HeaderExtractor("X-Entitlements"), wantError: "Missing X-Entitlements header"},
+		{name: "cookie", extractor: CookieExtractor("ents"), wantError: "Missing or invalid entitlements"},
+		{
+			name: "extraction error",
+			extractor: ExtractorFunc(func(r *http.Request) (string, error) {
+				return "reports", errors.New("malformed entitlements")
+			}),
+			wantError: "Missing or invalid entitlements",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.LogLevel = LogLevelNone
+			config.EntitlementExtractor = tt.extractor
+			m := NewMiddlewareWithChecker(fake, config)
+
+			req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+			entitlements := m.Entitlements(req)
+			if entitlements != "" {
+				t.Fatalf("entitlements %q, want empty", entitlements)
+			}
+
+			result := m.Authorize(req.Context(), "reports.view", entitlements)
+			if result.Outcome != OutcomeUnauthorized || !errors.Is(result.Err, ErrMissingEntitlements) {
+				t.Fatalf("outcome %v, err %v, want unauthorized with ErrMissingEntitlements", result.Outcome, result.Err)
+			}
+			if got := result.Body()["error"]; got != tt.wantError {
+				t.Errorf("error %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Package fiber предоставляет middleware проверки прав доступа locator-ars
+// для фреймворка Fiber. Поведение совпадает с RequireAction для gin:
+// те же заголовки, политика AllowOnFailure, статусы и JSON-тела ответов.
+package fiber
+
+import (
+	"context"
+	"strings"
+
+	fiberv2 "github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+)
+
+// DecisionFromContext возвращает ответ сервиса, сохраненный middleware при разрешении доступа.
+// Возвращает false, если решения нет, например при разрешении по политике AllowOnFailure
+func DecisionFromContext(c *fiberv2.Ctx) (*locatorars.AccessResponse, bool) {
+	decision, ok := c.Locals(locatorars.DecisionContextKey).(*locatorars.AccessResponse)
+	return decision, ok && decision != nil
+}
+
+// RequireAction создает middleware, который требует указанное действие.
+// Для отмены проверки используется контекст, установленный через c.SetUserContext
+func RequireAction(m *locatorars.Middleware, action string, opts ...locatorars.RouteOption) fiberv2.Handler {
+	return middleware(func(c *fiberv2.Ctx) locatorars.AuthResult {
+		return m.Authorize(userContext(c), action, entitlements(m, c), opts...)
+	})
+}
+
+// RequireAny создает middleware, который требует хотя бы одно из указанных действий.
+// Повторы убираются, пустой список вызывает панику при регистрации маршрута
+func RequireAny(m *locatorars.Middleware, actions ...string) fiberv2.Handler {
+	actions = locatorars.UniqueActions("RequireAny", actions)
+	return middleware(func(c *fiberv2.Ctx) locatorars.AuthResult {
+		return m.AuthorizeAny(userContext(c), entitlements(m, c), actions...)
+	})
+}
+
+// RequireAll создает middleware, который требует все указанные действия.
+// Повторы убираются, пустой список вызывает панику при регистрации маршрута
+func RequireAll(m *locatorars.Middleware, actions ...string) fiberv2.Handler {
+	actions = locatorars.UniqueActions("RequireAll", actions)
+	return middleware(func(c *fiberv2.Ctx) locatorars.AuthResult {
+		return m.AuthorizeAll(userContext(c), entitlements(m, c), actions...)
+	})
+}
+
+// RequireExpr создает middleware, который требует истинности логического выражения над действиями.
+// Синтаксическая ошибка выражения возвращается при создании middleware
+func RequireExpr(m *locatorars.Middleware, source string, opts ...locatorars.RouteOption) (fiberv2.Handler, error) {
+	expr, err := locatorars.ParseExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	return middleware(func(c *fiberv2.Ctx) locatorars.AuthResult {
+		return m.AuthorizeExpr(userContext(c), expr, entitlements(m, c), opts...)
+	}), nil
+}
+
+// userContext возвращает пользовательский контекст с данными запроса для журнала аудита.
+// Строки копируются, так как fasthttp переиспользует буферы
+func userContext(c *fiberv2.Ctx) context.Context {
+	return locatorars.ContextWithRequestInfo(c.UserContext(), locatorars.RequestInfo{
+		ClientIP:  strings.Clone(c.IP()),
+		Route:     c.Route().Path,
+		Method:    strings.Clone(c.Method()),
+		RequestID: strings.Clone(c.Get(locatorars.RequestIDHeader)),
+	})
+}
+
+// entitlements извлекает Entitlements настроенным в Middleware EntitlementExtractor.
+// Запрос fasthttp преобразуется в *http.Request, значения c.Locals доступны
+// ContextKeyExtractor. Результат копируется, так как fasthttp переиспользует буферы
+func entitlements(m *locatorars.Middleware, c *fiberv2.Ctx) string {
+	req, err := adaptor.ConvertRequest(c, false)
+	if err != nil {
+		return ""
+	}
+	req = req.WithContext(localsContext{Context: c.UserContext(), c: c})
+	return strings.Clone(m.Entitlements(req))
+}
+
+// localsContext дополняет пользовательский контекст значениями из c.Locals
+type localsContext struct {
+	context.Context
+	c *fiberv2.Ctx
+}
+
+func (ctx localsContext) Value(key interface{}) interface{} {
+	if value := ctx.c.Locals(key); value != nil {
+		return value
+	}
+	return ctx.Context.Value(key)
+}
+
+// middleware применяет результат проверки authorize к запросу Fiber
+func middleware(authorize func(c *fiberv2.Ctx) locatorars.AuthResult) fiberv2.Handler {
+	return func(c *fiberv2.Ctx) error {
+		result := authorize(c)
+		switch {
+		case result.Allowed():
+			if result.Decision != nil {
+				c.Locals(locatorars.DecisionContextKey, result.Decision)
+			}
+			return c.Next()
+		case result.Outcome == locatorars.OutcomeCancelled:
+			// Клиент отменил запрос, отвечать некому
+			return nil
+		default:
+			return c.Status(result.StatusCode()).JSON(result.Body())
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package fiber_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	fiberv2 "github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/conformance"
+	arsfiber "github.com/LT-Devs/locator-ars-go-lib/fiber"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(m *locatorars.Middleware, action string) http.Handler {
+		app := fiberv2.New()
+		app.Get("/", arsfiber.RequireAction(m, action), func(c *fiberv2.Ctx) error {
+			decision, ok := arsfiber.DecisionFromContext(c)
+			if !ok {
+				return c.SendString("")
+			}
+			return c.SendString(fmt.Sprint(decision.User["name"]))
+		})
+		return adaptor.FiberApp(app)
+	})
+}
+
+func TestConformanceActions(t *testing.T) {
+	newHandler := func(require func(m *locatorars.Middleware, actions ...string) fiberv2.Handler) conformance.NewActionsHandler {
+		return func(m *locatorars.Middleware, actions ...string) http.Handler {
+			app := fiberv2.New()
+			app.Get("/", require(m, actions...), func(c *fiberv2.Ctx) error {
+				return c.SendStatus(http.StatusOK)
+			})
+			return adaptor.FiberApp(app)
+		}
+	}
+	conformance.RunActions(t, newHandler(arsfiber.RequireAny), newHandler(arsfiber.RequireAll))
+}
@@ -0,0 +1,35 @@
+module github.com/LT-Devs/locator-ars-go-lib/fiber
+
+go 1.24
+
+require (
+	github.com/LT-Devs/locator-ars-go-lib v0.2.0
+	github.com/gofiber/fiber/v2 v2.52.9
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/mattn/go-colorable v0.1.14 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/mattn/go-runewidth v0.0.16 // indirect
+	github.com/pelletier/go-toml/v2 v2.0.8 // indirect
+	github.com/rivo/uniseg v0.2.0 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.51.0 // indirect
+	github.com/valyala/tcplisten v1.0.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel v1.38.0 // indirect
+	go.opentelemetry.io/otel/metric v1.38.0 // indirect
+	go.opentelemetry.io/otel/trace v1.38.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// В рабочей копии репозитория модуль собирается с корневым модулем из соседнего каталога.
+// Зависимые модули replace не учитывают и получают версию из require,
+// поэтому корневой модуль тегируется раньше подмодулей (см. README, «Выпуск версий»)
+replace github.com/LT-Devs/locator-ars-go-lib => ../
@@ -0,0 +1,160 @@
+// Package gin предоставляет middleware проверки прав доступа locator-ars
+// для фреймворка gin. Корневой пакет locatorars не зависит от gin,
+// поэтому сервисы на net/http, chi и других фреймворках его не подключают.
+//
+//	r.GET("/reports", arsgin.RequireAction(arsMiddleware, "reports.view"), listReports)
+package gin
+
+import (
+	"context"
+
+	gingonic "github.com/gin-gonic/gin"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+)
+
+// DecisionFromContext возвращает ответ сервиса, сохраненный middleware при разрешении доступа.
+// Возвращает false, если решения нет, например если доступ был разрешен
+// по политике AllowOnFailure без ответа сервиса
+func DecisionFromContext(c *gingonic.Context) (*locatorars.AccessResponse, bool) {
+	value, ok := c.Get(locatorars.DecisionContextKey)
+	if !ok {
+		return nil, false
+	}
+	decision, ok := value.(*locatorars.AccessResponse)
+	return decision, ok && decision != nil
+}
+
+// RequireAction создает middleware, который требует указанное действие.
+// Опции маршрута переопределяют политику AllowOnFailure:
+//
+//	r.POST("/pay", arsgin.RequireAction(arsMiddleware, "pay", locatorars.FailClosed()), pay)
+func RequireAction(m *locatorars.Middleware, action string, opts ...locatorars.RouteOption) gingonic.HandlerFunc {
+	return middleware(func(c *gingonic.Context) locatorars.AuthResult {
+		// Передаем контекст входящего запроса, чтобы отмена запроса клиентом прерывала и проверку прав
+		return m.Authorize(requestContext(c), action, Entitlements(m, c), opts...)
+	})
+}
+
+// RequireAny создает middleware, который пропускает запрос, если разрешено
+// хотя бы одно из указанных действий. Действия проверяются параллельно,
+// проверка завершается при первом разрешающем решении
+func RequireAny(m *locatorars.Middleware, actions ...string) gingonic.HandlerFunc {
+	actions = locatorars.UniqueActions("RequireAny", actions)
+	return middleware(func(c *gingonic.Context) locatorars.AuthResult {
+		return m.AuthorizeAny(requestContext(c), Entitlements(m, c), actions...)
+	})
+}
+
+// RequireAll создает middleware, который пропускает запрос, только если разрешены
+// все указанные действия. Действия проверяются параллельно, проверка завершается
+// при первом запрещающем решении, поэтому в ответе 403 перечисляются действия,
+// запрет на которые был получен к этому моменту
+func RequireAll(m *locatorars.Middleware, actions ...string) gingonic.HandlerFunc {
+	actions = locatorars.UniqueActions("RequireAll", actions)
+	return middleware(func(c *gingonic.Context) locatorars.AuthResult {
+		return m.AuthorizeAll(requestContext(c), Entitlements(m, c), actions...)
+	})
+}
+
+// RequireExpr создает middleware, который пропускает запрос, если истинно логическое
+// выражение над действиями, например "(reports.view && region.eu) || admin".
+// Выражение разбирается при регистрации маршрута, синтаксическая ошибка возвращается сразу.
+// Опции маршрута применяются так же, как в RequireAction
+func RequireExpr(m *locatorars.Middleware, source string, opts ...locatorars.RouteOption) (gingonic.HandlerFunc, error) {
+	expr, err := locatorars.ParseExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	return middleware(func(c *gingonic.Context) locatorars.AuthResult {
+		return m.AuthorizeExpr(requestContext(c), expr, Entitlements(m, c), opts...)
+	}), nil
+}
+
+// MustRequireExpr аналогичен RequireExpr, но паникует при синтаксической ошибке,
+// что позволяет использовать его прямо при регистрации маршрутов
+func MustRequireExpr(m *locatorars.Middleware, source string, opts ...locatorars.RouteOption) gingonic.HandlerFunc {
+	handler, err := RequireExpr(m, source, opts...)
+	if err != nil {
+		panic("locatorars: " + err.Error())
+	}
+	return handler
+}
+
+// CheckAccessFromContext проверяет права доступа, извлекая Entitlements из gin.Context.
+// Удобно для использования в обработчиках. Без Entitlements возвращает false
+func CheckAccessFromContext(m *locatorars.Middleware, c *gingonic.Context, action string) bool {
+	entitlements := Entitlements(m, c)
+	if entitlements == "" {
+		return false
+	}
+	return m.CheckAccessContext(requestContext(c), action, entitlements)
+}
+
+// CheckActionsFromContext проверяет несколько действий, извлекая Entitlements из gin.Context.
+// Удобно для построения карты прав при отрисовке меню и интерфейса
+func CheckActionsFromContext(m *locatorars.Middleware, c *gingonic.Context, actions ...string) map[string]bool {
+	entitlements := Entitlements(m, c)
+	if entitlements == "" {
+		results := make(map[string]bool, len(actions))
+		for _, action := range actions {
+			results[action] = false
+		}
+		return results
+	}
+
+	results, _ := m.CheckActions(c.Request.Context(), entitlements, actions)
+	return results
+}
+
+// Entitlements извлекает Entitlements из запроса gin настроенным EntitlementExtractor.
+// Значения, сохраненные через c.Set, доступны ContextKeyExtractor по строковому ключу
+func Entitlements(m *locatorars.Middleware, c *gingonic.Context) string {
+	return m.Entitlements(c.Request.WithContext(valueContext{Context: c.Request.Context(), c: c}))
+}
+
+// valueContext дополняет контекст запроса значениями из gin.Context
+type valueContext struct {
+	context.Context
+	c *gingonic.Context
+}
+
+func (ctx valueContext) Value(key interface{}) interface{} {
+	if name, ok := key.(string); ok {
+		if value, exists := ctx.c.Get(name); exists {
+			return value
+		}
+	}
+	return ctx.Context.Value(key)
+}
+
+// requestContext возвращает контекст входящего запроса с данными для журнала аудита
+func requestContext(c *gingonic.Context) context.Context {
+	return locatorars.ContextWithRequestInfo(c.Request.Context(), locatorars.RequestInfo{
+		ClientIP:  c.ClientIP(),
+		Route:     c.FullPath(),
+		Method:    c.Request.Method,
+		RequestID: c.GetHeader(locatorars.RequestIDHeader),
+	})
+}
+
+// middleware применяет результат проверки authorize к gin.Context: пропускает запрос
+// или прерывает его с соответствующим статусом и JSON-телом
+func middleware(authorize func(c *gingonic.Context) locatorars.AuthResult) gingonic.HandlerFunc {
+	return func(c *gingonic.Context) {
+		result := authorize(c)
+		switch {
+		case result.Allowed():
+			if result.Decision != nil {
+				// Сохраняем решение, чтобы обработчик мог получить пользователя через DecisionFromContext
+				c.Set(locatorars.DecisionContextKey, result.Decision)
+			}
+			c.Next()
+		case result.Outcome == locatorars.OutcomeCancelled:
+			// Клиент отменил запрос, отвечать некому
+			c.Abort()
+		default:
+			c.AbortWithStatusJSON(result.StatusCode(), result.Body())
+		}
+	}
+}
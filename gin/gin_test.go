@@ -0,0 +1,45 @@
+package gin_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	gingonic "github.com/gin-gonic/gin"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/conformance"
+	arsgin "github.com/LT-Devs/locator-ars-go-lib/gin"
+)
+
+func TestConformance(t *testing.T) {
+	gingonic.SetMode(gingonic.TestMode)
+
+	conformance.Run(t, func(m *locatorars.Middleware, action string) http.Handler {
+		r := gingonic.New()
+		r.GET("/", arsgin.RequireAction(m, action), func(c *gingonic.Context) {
+			decision, ok := arsgin.DecisionFromContext(c)
+			if !ok {
+				c.String(http.StatusOK, "")
+				return
+			}
+			c.String(http.StatusOK, fmt.Sprint(decision.User["name"]))
+		})
+		return r
+	})
+}
+
+func TestConformanceActions(t *testing.T) {
+	gingonic.SetMode(gingonic.TestMode)
+
+	newHandler := func(require func(m *locatorars.Middleware, actions ...string) gingonic.HandlerFunc) conformance.NewActionsHandler {
+		return func(m *locatorars.Middleware, actions ...string) http.Handler {
+			r := gingonic.New()
+			r.GET("/", require(m, actions...), func(c *gingonic.Context) {
+				c.Status(http.StatusOK)
+			})
+			return r
+		}
+	}
+	conformance.RunActions(t, newHandler(arsgin.RequireAny), newHandler(arsgin.RequireAll))
+}
@@ -0,0 +1,165 @@
+// Package grpc предоставляет серверные перехватчики gRPC с проверкой прав
+// доступа locator-ars. Entitlements извлекаются из входящих метаданных,
+// а полное имя метода сопоставляется с действием по настраиваемой таблице.
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	grpcgo "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+)
+
+// DefaultEntitlementsKey ключ метаданных с Entitlements по умолчанию
+const DefaultEntitlementsKey = "x-authentik-entitlements"
+
+// Config определяет сопоставление методов gRPC с действиями
+type Config struct {
+	// Таблица сопоставления полного имени метода ("/package.Service/Method") с действием
+	Methods map[string]string
+
+	// Действие для методов, отсутствующих в таблице Methods.
+	// Если не задано, такие методы обрабатываются согласно AllowUnmapped
+	DefaultAction string
+
+	// Разрешить вызов методов, для которых не найдено действие.
+	// По умолчанию такие вызовы отклоняются с codes.PermissionDenied
+	AllowUnmapped bool
+
+	// Ключ входящих метаданных с Entitlements
+	// По умолчанию: "x-authentik-entitlements"
+	EntitlementsKey string
+}
+
+// decisionKey тип ключа контекста для ответа сервиса
+type decisionKey struct{}
+
+// DecisionFromContext возвращает ответ сервиса, сохраненный перехватчиком при разрешении доступа
+func DecisionFromContext(ctx context.Context) (*locatorars.AccessResponse, bool) {
+	decision, ok := ctx.Value(decisionKey{}).(*locatorars.AccessResponse)
+	return decision, ok && decision != nil
+}
+
+// UnaryServerInterceptor создает перехватчик унарных вызовов с проверкой прав доступа
+func UnaryServerInterceptor(m *locatorars.Middleware, config Config) grpcgo.UnaryServerInterceptor {
+	a := newAuthorizer(m, config)
+	return func(ctx context.Context, req interface{}, info *grpcgo.UnaryServerInfo, handler grpcgo.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor создает перехватчик потоковых вызовов с проверкой прав доступа.
+// Проверка выполняется один раз при открытии потока
+func StreamServerInterceptor(m *locatorars.Middleware, config Config) grpcgo.StreamServerInterceptor {
+	a := newAuthorizer(m, config)
+	return func(srv interface{}, ss grpcgo.ServerStream, info *grpcgo.StreamServerInfo, handler grpcgo.StreamHandler) error {
+		ctx, err := a.authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream подменяет контекст потока, чтобы обработчик получил ответ сервиса
+type serverStream struct {
+	grpcgo.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorizer общая логика перехватчиков
+type authorizer struct {
+	middleware *locatorars.Middleware
+	config     Config
+}
+
+func newAuthorizer(m *locatorars.Middleware, config Config) *authorizer {
+	if config.EntitlementsKey == "" {
+		config.EntitlementsKey = DefaultEntitlementsKey
+	}
+	// Ключи метаданных gRPC всегда в нижнем регистре
+	config.EntitlementsKey = strings.ToLower(config.EntitlementsKey)
+	return &authorizer{middleware: m, config: config}
+}
+
+// authorize проверяет доступ к методу и возвращает контекст с ответом сервиса
+// или ошибку gRPC со статусом, соответствующим итогу проверки
+func (a *authorizer) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	action, ok := a.config.Methods[fullMethod]
+	if !ok {
+		action = a.config.DefaultAction
+	}
+	if action == "" {
+		if a.config.AllowUnmapped {
+			return ctx, nil
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "no access action configured for method %s", fullMethod)
+	}
+
+	var entitlements string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(a.config.EntitlementsKey); len(values) > 0 {
+			entitlements = values[0]
+		}
+	}
+
+	result := a.middleware.Authorize(requestContext(ctx, fullMethod), action, entitlements)
+	if result.Allowed() {
+		if result.Decision != nil {
+			ctx = context.WithValue(ctx, decisionKey{}, result.Decision)
+		}
+		return ctx, nil
+	}
+	if result.Outcome == locatorars.OutcomeUnauthorized {
+		return nil, status.Errorf(codes.Unauthenticated, "missing %s metadata", a.config.EntitlementsKey)
+	}
+	return nil, status.Error(statusCode(result.Outcome), result.Body()["error"].(string))
+}
+
+// requestContext возвращает контекст с данными вызова для журнала аудита
+func requestContext(ctx context.Context, fullMethod string) context.Context {
+	info := locatorars.RequestInfo{Route: fullMethod, Method: "gRPC"}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		info.ClientIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(info.ClientIP); err == nil {
+			info.ClientIP = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(strings.ToLower(locatorars.RequestIDHeader)); len(values) > 0 {
+			info.RequestID = values[0]
+		}
+	}
+	return locatorars.ContextWithRequestInfo(ctx, info)
+}
+
+// statusCode сопоставляет итог проверки с кодом gRPC
+func statusCode(outcome locatorars.Outcome) codes.Code {
+	switch outcome {
+	case locatorars.OutcomeDenied:
+		return codes.PermissionDenied
+	case locatorars.OutcomeUnauthorized:
+		return codes.Unauthenticated
+	case locatorars.OutcomeTimeout:
+		return codes.DeadlineExceeded
+	case locatorars.OutcomeCancelled:
+		return codes.Canceled
+	default:
+		return codes.Unavailable
+	}
+}
@@ -0,0 +1,213 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	grpcgo "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	arsgrpc "github.com/LT-Devs/locator-ars-go-lib/grpc"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+const (
+	checkMethod = "/grpc.health.v1.Health/Check"
+	watchMethod = "/grpc.health.v1.Health/Watch"
+)
+
+// healthServer отвечает SERVING, если перехватчик передал обработчику ответ сервиса,
+// и NOT_SERVING, если доступ разрешен без него
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func decisionStatus(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	if _, ok := arsgrpc.DecisionFromContext(ctx); ok {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+func (healthServer) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	return &healthpb.HealthCheckResponse{Status: decisionStatus(ctx)}, nil
+}
+
+func (healthServer) Watch(_ *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	return stream.Send(&healthpb.HealthCheckResponse{Status: decisionStatus(stream.Context())})
+}
+
+// newClient запускает gRPC-сервер с перехватчиками поверх bufconn и возвращает клиент к нему
+func newClient(t *testing.T, m *locatorars.Middleware, config arsgrpc.Config) healthpb.HealthClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpcgo.NewServer(
+		grpcgo.UnaryInterceptor(arsgrpc.UnaryServerInterceptor(m, config)),
+		grpcgo.StreamInterceptor(arsgrpc.StreamServerInterceptor(m, config)),
+	)
+	healthpb.RegisterHealthServer(server, healthServer{})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpcgo.NewClient("passthrough:///bufconn",
+		grpcgo.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpcgo.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+// call выполняет унарный или потоковый вызов и возвращает код gRPC и статус из ответа
+func call(ctx context.Context, client healthpb.HealthClient, stream bool) (codes.Code, healthpb.HealthCheckResponse_ServingStatus) {
+	var resp *healthpb.HealthCheckResponse
+	var err error
+	if stream {
+		var watch healthpb.Health_WatchClient
+		if watch, err = client.Watch(ctx, &healthpb.HealthCheckRequest{}); err == nil {
+			resp, err = watch.Recv()
+		}
+	} else {
+		resp, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	}
+	if err != nil {
+		return status.Code(err), healthpb.HealthCheckResponse_UNKNOWN
+	}
+	return codes.OK, resp.Status
+}
+
+func TestInterceptors(t *testing.T) {
+	ars := locatorarstest.NewServer(t)
+	ars.Allow("health.check", "health")
+	ars.Allow("health.watch", "health")
+	ars.Allow("health.default", "health")
+	ars.Fail("health.broken", http.StatusServiceUnavailable)
+
+	methods := map[string]string{checkMethod: "health.check", watchMethod: "health.watch"}
+	broken := map[string]string{checkMethod: "health.broken", watchMethod: "health.broken"}
+
+	tests := []struct {
+		name           string
+		config         arsgrpc.Config
+		allowOnFailure bool
+		entitlements   string
+		wantCode       codes.Code
+		wantStatus     healthpb.HealthCheckResponse_ServingStatus
+		wantCalls      bool
+	}{
+		{
+			name:         "allowed",
+			config:       arsgrpc.Config{Methods: methods},
+			entitlements: "health",
+			wantCode:     codes.OK,
+			wantStatus:   healthpb.HealthCheckResponse_SERVING,
+			wantCalls:    true,
+		},
+		{
+			name:         "denied",
+			config:       arsgrpc.Config{Methods: methods},
+			entitlements: "reports",
+			wantCode:     codes.PermissionDenied,
+			wantCalls:    true,
+		},
+		{
+			name:     "missing metadata",
+			config:   arsgrpc.Config{Methods: methods},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:         "custom entitlements key",
+			config:       arsgrpc.Config{Methods: methods, EntitlementsKey: "X-Entitlements"},
+			entitlements: "health",
+			wantCode:     codes.Unauthenticated,
+		},
+		{
+			name:         "service unavailable fails closed",
+			config:       arsgrpc.Config{Methods: broken},
+			entitlements: "health",
+			wantCode:     codes.Unavailable,
+			wantCalls:    true,
+		},
+		{
+			name:           "service unavailable fails open",
+			config:         arsgrpc.Config{Methods: broken},
+			allowOnFailure: true,
+			entitlements:   "health",
+			wantCode:       codes.OK,
+			wantStatus:     healthpb.HealthCheckResponse_NOT_SERVING,
+			wantCalls:      true,
+		},
+		{
+			name:         "unmapped method rejected",
+			config:       arsgrpc.Config{},
+			entitlements: "health",
+			wantCode:     codes.PermissionDenied,
+		},
+		{
+			name:         "unmapped method allowed",
+			config:       arsgrpc.Config{AllowUnmapped: true},
+			entitlements: "health",
+			wantCode:     codes.OK,
+			wantStatus:   healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			name:         "default action",
+			config:       arsgrpc.Config{DefaultAction: "health.default", AllowUnmapped: true},
+			entitlements: "health",
+			wantCode:     codes.OK,
+			wantStatus:   healthpb.HealthCheckResponse_SERVING,
+			wantCalls:    true,
+		},
+		{
+			name:         "default action denied",
+			config:       arsgrpc.Config{DefaultAction: "health.default"},
+			entitlements: "reports",
+			wantCode:     codes.PermissionDenied,
+			wantCalls:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		config := ars.Config()
+		config.AllowOnFailure = tt.allowOnFailure
+		client := newClient(t, locatorars.NewMiddleware(config), tt.config)
+
+		for _, stream := range []bool{false, true} {
+			name := tt.name + "/unary"
+			if stream {
+				name = tt.name + "/stream"
+			}
+			t.Run(name, func(t *testing.T) {
+				ars.ResetRequests()
+
+				ctx := context.Background()
+				if tt.entitlements != "" {
+					ctx = metadata.AppendToOutgoingContext(ctx, arsgrpc.DefaultEntitlementsKey, tt.entitlements)
+				}
+				code, servingStatus := call(ctx, client, stream)
+				if code != tt.wantCode {
+					t.Fatalf("code %v, want %v", code, tt.wantCode)
+				}
+				if code == codes.OK && servingStatus != tt.wantStatus {
+					t.Errorf("status %v, want %v", servingStatus, tt.wantStatus)
+				}
+				if calls := len(ars.Requests()); (calls > 0) != tt.wantCalls {
+					t.Errorf("access service called %d times, want calls: %v", calls, tt.wantCalls)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package locatorars
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// decisionKey тип ключа контекста запроса для решения сервиса
+type decisionKey struct{}
+
+// DecisionFromRequest возвращает решение сервиса, сохраненное в контексте запроса
+// адаптером net/http. Возвращает false, если решения нет
+func DecisionFromRequest(r *http.Request) (*AccessResponse, bool) {
+	decision, ok := r.Context().Value(decisionKey{}).(*AccessResponse)
+	return decision, ok && decision != nil
+}
+
+// RequireActionHTTP создает стандартный net/http middleware, который требует указанное действие.
+// Совместим с chi и любыми роутерами, принимающими func(http.Handler) http.Handler
+func (m *Middleware) RequireActionHTTP(action string, opts ...RouteOption) func(http.Handler) http.Handler {
+	options := newRouteOptions(opts)
+	return m.httpMiddleware(func(r *http.Request) AuthResult {
+		s := m.snapshot()
+		return s.authorize(r.Context(), action, s.entitlements(r), options)
+	})
+}
+
+// RequireAnyHTTP аналог RequireAny для net/http
+func (m *Middleware) RequireAnyHTTP(actions ...string) func(http.Handler) http.Handler {
+	actions = UniqueActions("RequireAnyHTTP", actions)
+	return m.httpMiddleware(func(r *http.Request) AuthResult {
+		s := m.snapshot()
+		return s.authorizeAny(r.Context(), s.entitlements(r), actions...)
+	})
+}
+
+// RequireAllHTTP аналог RequireAll для net/http
+func (m *Middleware) RequireAllHTTP(actions ...string) func(http.Handler) http.Handler {
+	actions = UniqueActions("RequireAllHTTP", actions)
+	return m.httpMiddleware(func(r *http.Request) AuthResult {
+		s := m.snapshot()
+		return s.authorizeAll(r.Context(), s.entitlements(r), actions...)
+	})
+}
+
+// RequireExprHTTP аналог RequireExpr для net/http. Синтаксическая ошибка выражения
+// возвращается при создании middleware
+func (m *Middleware) RequireExprHTTP(source string, opts ...RouteOption) (func(http.Handler) http.Handler, error) {
+	expr, err := ParseExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	options := newRouteOptions(opts)
+	return m.httpMiddleware(func(r *http.Request) AuthResult {
+		s := m.snapshot()
+		return s.authorizeExpr(r.Context(), expr, s.entitlements(r), options)
+	}), nil
+}
+
+// httpMiddleware оборачивает проверку authorize в net/http middleware
+func (m *Middleware) httpMiddleware(authorize func(r *http.Request) AuthResult) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result := authorize(r.WithContext(ContextWithRequestInfo(r.Context(), HTTPRequestInfo(r))))
+			switch {
+			case result.Allowed():
+				if result.Decision != nil {
+					r = r.WithContext(context.WithValue(r.Context(), decisionKey{}, result.Decision))
+				}
+				next.ServeHTTP(w, r)
+			case result.Outcome == OutcomeCancelled:
+				// Клиент отменил запрос, отвечать некому
+			default:
+				WriteJSONError(w, result)
+			}
+		})
+	}
+}
+
+// WriteJSONError записывает в w статус и JSON-тело отказа из result.
+// Используется адаптерами на базе net/http
+func WriteJSONError(w http.ResponseWriter, result AuthResult) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(result.StatusCode())
+	_ = json.NewEncoder(w).Encode(result.Body())
+}
@@ -0,0 +1,36 @@
+package locatorars_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(m *locatorars.Middleware, action string) http.Handler {
+		mux := http.NewServeMux()
+		mux.Handle("/", m.RequireActionHTTP(action)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if decision, ok := locatorars.DecisionFromRequest(r); ok {
+				fmt.Fprint(w, decision.User["name"])
+			}
+		})))
+		return mux
+	})
+}
+
+func TestConformanceActions(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	conformance.RunActions(t,
+		func(m *locatorars.Middleware, actions ...string) http.Handler {
+			return m.RequireAnyHTTP(actions...)(ok)
+		},
+		func(m *locatorars.Middleware, actions ...string) http.Handler {
+			return m.RequireAllHTTP(actions...)(ok)
+		},
+	)
+}
@@ -0,0 +1,511 @@
+package locatorars
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWTClaim           = "entitlements"
+	defaultJWKSRefresh        = time.Hour
+	defaultJWTLeeway          = 30 * time.Second
+	minJWKSRefreshOnUnknownID = time.Minute
+)
+
+// Ошибки проверки JWT
+var (
+	ErrTokenInvalid = errors.New("invalid JWT")
+	ErrTokenExpired = errors.New("JWT is expired")
+)
+
+// JWTConfig определяет параметры локальной проверки JWT от Authentik
+type JWTConfig struct {
+	// Заголовок с JWT
+	// По умолчанию: "X-Authentik-Jwt"
+	Header string
+
+	// URL набора ключей JWKS, например
+	// "https://authentik.example.com/application/o/<slug>/jwks/".
+	// Указывается URL или файл
+	JWKSURL string
+
+	// Путь к файлу с набором ключей JWKS
+	JWKSFile string
+
+	// Период обновления набора ключей
+	// По умолчанию: 1 час
+	RefreshInterval time.Duration
+
+	// HTTP-клиент для загрузки JWKS (если nil, используется клиент с таймаутом 5 секунд)
+	HTTPClient *http.Client
+
+	// Ожидаемый издатель (claim iss), не проверяется, если пусто
+	Issuer string
+
+	// Допустимые получатели (claim aud), не проверяется, если пусто
+	Audience []string
+
+	// Claim с Entitlements, вложенные claim указываются через точку
+	// По умолчанию: "entitlements"
+	Claim string
+
+	// Допустимое расхождение часов при проверке exp и nbf
+	// По умолчанию: 30 секунд
+	Leeway time.Duration
+}
+
+// JWTExtractor реализует EntitlementExtractor: проверяет подпись и claims JWT
+// по набору ключей JWKS и извлекает Entitlements из настроенного claim.
+// Защищает от подделки заголовка с Entitlements при обходе прокси
+type JWTExtractor struct {
+	config JWTConfig
+	client *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]crypto.PublicKey
+	// lastRefresh время последней загрузки набора или попытки внеочередного обновления
+	lastRefresh time.Time
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewJWTExtractor создает извлекатель с проверкой JWT и загружает набор ключей.
+// Ключи периодически обновляются в фоне, для остановки обновления вызовите Close
+func NewJWTExtractor(config JWTConfig) (*JWTExtractor, error) {
+	if (config.JWKSURL == "") == (config.JWKSFile == "") {
+		return nil, fmt.Errorf("exactly one of JWKSURL and JWKSFile must be set")
+	}
+	if config.Header == "" {
+		config.Header = DefaultJWTHeader
+	}
+	if config.Claim == "" {
+		config.Claim = defaultJWTClaim
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = defaultJWKSRefresh
+	}
+	if config.Leeway == 0 {
+		config.Leeway = defaultJWTLeeway
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	e := &JWTExtractor{
+		config: config,
+		client: client,
+		stop:   make(chan struct{}),
+	}
+	if err := e.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+
+	go e.refreshLoop()
+	return e, nil
+}
+
+// Close останавливает фоновое обновление набора ключей
+func (e *JWTExtractor) Close() {
+	e.once.Do(func() { close(e.stop) })
+}
+
+// Extract проверяет JWT из заголовка и возвращает Entitlements из claim.
+// Если токена нет, возвращает пустую строку без ошибки
+func (e *JWTExtractor) Extract(r *http.Request) (string, error) {
+	token := bearerToken(r.Header.Get(e.config.Header))
+	if token == "" {
+		return "", nil
+	}
+
+	claims, err := e.Verify(r.Context(), token)
+	if err != nil {
+		return "", err
+	}
+	return claimEntitlements(claims, e.config.Claim)
+}
+
+// Verify проверяет подпись и стандартные claims токена и возвращает его claims
+func (e *JWTExtractor) Verify(ctx context.Context, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected 3 segments, got %d", ErrTokenInvalid, len(parts))
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed header: %v", ErrTokenInvalid, err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header: %v", ErrTokenInvalid, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature: %v", ErrTokenInvalid, err)
+	}
+
+	key, err := e.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed payload: %v", ErrTokenInvalid, err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims: %v", ErrTokenInvalid, err)
+	}
+
+	if err := e.validateClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validateClaims проверяет exp, nbf, iss и aud
+func (e *JWTExtractor) validateClaims(claims map[string]interface{}, now time.Time) error {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: missing exp claim", ErrTokenInvalid)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(e.config.Leeway)) {
+		return ErrTokenExpired
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok {
+		if now.Add(e.config.Leeway).Before(time.Unix(int64(nbf), 0)) {
+			return fmt.Errorf("%w: token is not valid yet", ErrTokenInvalid)
+		}
+	}
+
+	if e.config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != e.config.Issuer {
+			return fmt.Errorf("%w: unexpected issuer %q", ErrTokenInvalid, iss)
+		}
+	}
+
+	if len(e.config.Audience) > 0 {
+		var audiences []string
+		switch aud := claims["aud"].(type) {
+		case string:
+			audiences = []string{aud}
+		case []interface{}:
+			for _, item := range aud {
+				if s, ok := item.(string); ok {
+					audiences = append(audiences, s)
+				}
+			}
+		}
+
+		matched := false
+		for _, expected := range e.config.Audience {
+			for _, actual := range audiences {
+				if expected == actual {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%w: unexpected audience %v", ErrTokenInvalid, audiences)
+		}
+	}
+
+	return nil
+}
+
+// key возвращает ключ по идентификатору. Неизвестный идентификатор приводит
+// к внеочередному обновлению набора ключей, но не чаще раза в минуту
+func (e *JWTExtractor) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok := e.lookup(kid); ok {
+		return key, nil
+	}
+
+	// Время попытки фиксируется до загрузки: неудачная загрузка тоже ограничивает
+	// частоту обновлений, а параллельные запросы не загружают набор одновременно
+	e.mu.Lock()
+	canRefresh := time.Since(e.lastRefresh) >= minJWKSRefreshOnUnknownID
+	if canRefresh {
+		e.lastRefresh = time.Now()
+	}
+	e.mu.Unlock()
+	if canRefresh {
+		if err := e.Refresh(ctx); err != nil {
+			return nil, err
+		}
+		if key, ok := e.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: unknown key id %q", ErrTokenInvalid, kid)
+}
+
+// lookup ищет ключ по идентификатору. Если идентификатор в токене не указан,
+// а ключ в наборе один, используется он
+func (e *JWTExtractor) lookup(kid string) (crypto.PublicKey, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if kid == "" && len(e.keys) == 1 {
+		for _, key := range e.keys {
+			return key, true
+		}
+	}
+	key, ok := e.keys[kid]
+	return key, ok
+}
+
+// Refresh загружает набор ключей из файла или по URL
+func (e *JWTExtractor) Refresh(ctx context.Context) error {
+	data, err := e.loadJWKS(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.keys = keys
+	e.lastRefresh = time.Now()
+	e.mu.Unlock()
+	return nil
+}
+
+// loadJWKS читает набор ключей из настроенного источника
+func (e *JWTExtractor) loadJWKS(ctx context.Context) ([]byte, error) {
+	if e.config.JWKSFile != "" {
+		return os.ReadFile(e.config.JWKSFile)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.config.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned non-200 status: %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// refreshLoop периодически обновляет набор ключей. При ошибке
+// продолжают использоваться ранее загруженные ключи
+func (e *JWTExtractor) refreshLoop() {
+	ticker := time.NewTicker(e.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Ошибка не критична: до следующей попытки используются старые ключи
+			_ = e.Refresh(context.Background())
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// jsonWebKey ключ из набора JWKS
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS разбирает набор ключей. Ключи неподдерживаемых типов
+// и ключи шифрования пропускаются
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("malformed JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("malformed JWKS key %q: %w", jwk.Kid, err)
+		}
+		if key != nil {
+			keys[jwk.Kid] = key
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no supported signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey преобразует JWK в открытый ключ, для неподдерживаемых типов возвращает nil
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", jwk.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key size %d", len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+// decodeBigInt декодирует целое число из base64url
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// verifySignature проверяет подпись signingInput алгоритмом alg
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	case "EdDSA":
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(edKey, []byte(signingInput), signature) {
+			return fmt.Errorf("%w: signature verification failed", ErrTokenInvalid)
+		}
+		return nil
+	default:
+		// В том числе "none": неподписанные токены не принимаются
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrTokenInvalid, alg)
+	}
+
+	digest := hashSum(hash, signingInput)
+	var valid bool
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			valid = rsa.VerifyPKCS1v15(k, hash, digest, signature) == nil
+		case "PS":
+			valid = rsa.VerifyPSS(k, hash, digest, signature, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		if alg[:2] == "ES" {
+			size := (k.Curve.Params().BitSize + 7) / 8
+			if len(signature) == 2*size {
+				r := new(big.Int).SetBytes(signature[:size])
+				s := new(big.Int).SetBytes(signature[size:])
+				valid = ecdsa.Verify(k, digest, r, s)
+			}
+		}
+	}
+
+	if !valid {
+		return fmt.Errorf("%w: signature verification failed", ErrTokenInvalid)
+	}
+	return nil
+}
+
+// hashSum вычисляет хэш строки выбранной функцией
+func hashSum(hash crypto.Hash, input string) []byte {
+	switch hash {
+	case crypto.SHA384:
+		sum := sha512.Sum384([]byte(input))
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512([]byte(input))
+		return sum[:]
+	default:
+		sum := sha256.Sum256([]byte(input))
+		return sum[:]
+	}
+}
@@ -0,0 +1,352 @@
+package locatorars
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testKey — ключ подписи теста и его представление в JWKS
+type testKey struct {
+	kid     string
+	alg     string
+	private crypto.Signer
+}
+
+func newTestKey(t *testing.T, kid, alg string) testKey {
+	t.Helper()
+
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		t.Fatalf("unsupported test algorithm %q", alg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{kid: kid, alg: alg, private: private}
+}
+
+// jwk возвращает открытую часть ключа в формате JWK
+func (k testKey) jwk() map[string]string {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch public := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": k.kid, "use": "sig",
+			"n": encode(public.N.Bytes()), "e": encode(big.NewInt(int64(public.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": k.kid, "crv": "P-256",
+			"x": encode(public.X.FillBytes(make([]byte, 32))), "y": encode(public.Y.FillBytes(make([]byte, 32)))}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": k.kid, "crv": "Ed25519", "x": encode(public)}
+	}
+	return nil
+}
+
+// sign выпускает токен с заголовком header и claims, подписанный ключом
+func (k testKey) sign(t *testing.T, header, claims map[string]interface{}) string {
+	t.Helper()
+
+	encodeJSON := func(value interface{}) string {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signingInput := encodeJSON(header) + "." + encodeJSON(claims)
+
+	var signature []byte
+	var err error
+	switch private := k.private.(type) {
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		var r, s *big.Int
+		if r, s, err = ecdsa.Sign(rand.Reader, private, digest[:]); err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(private, []byte(signingInput))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// token выпускает токен со стандартным заголовком ключа
+func (k testKey) token(t *testing.T, claims map[string]interface{}) string {
+	return k.sign(t, map[string]interface{}{"alg": k.alg, "typ": "JWT", "kid": k.kid}, claims)
+}
+
+// jwksServer отдает текущий набор ключей и считает обращения
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	keys     []testKey
+	failing  bool
+	requests int
+}
+
+func newJWKSServer(t *testing.T, keys ...testKey) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if s.failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		set := make([]map[string]string, 0, len(s.keys))
+		for _, key := range s.keys {
+			set = append(set, key.jwk())
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": set})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...testKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+// setFailing переключает сервер в режим ответа 503
+func (s *jwksServer) setFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+func (s *jwksServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func newTestJWTExtractor(t *testing.T, config JWTConfig) *JWTExtractor {
+	t.Helper()
+	e, err := NewJWTExtractor(config)
+	if err != nil {
+		t.Fatalf("NewJWTExtractor: %v", err)
+	}
+	t.Cleanup(e.Close)
+	return e
+}
+
+// validClaims возвращает claims, действительные в течение часа
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"exp":          time.Now().Add(time.Hour).Unix(),
+		"entitlements": []string{"reports", "billing"},
+	}
+}
+
+func TestJWTExtractorAlgorithms(t *testing.T) {
+	keys := []testKey{
+		newTestKey(t, "rsa", "RS256"),
+		newTestKey(t, "ec", "ES256"),
+		newTestKey(t, "ed", "EdDSA"),
+	}
+	e := newTestJWTExtractor(t, JWTConfig{JWKSURL: newJWKSServer(t, keys...).URL})
+
+	for _, key := range keys {
+		t.Run(key.alg, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultJWTHeader, "Bearer "+key.token(t, validClaims()))
+
+			entitlements, err := e.Extract(req)
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if entitlements != "reports|billing" {
+				t.Errorf("entitlements %q, want %q", entitlements, "reports|billing")
+			}
+		})
+	}
+}
+
+func TestJWTExtractorRejectsForgedTokens(t *testing.T) {
+	key := newTestKey(t, "rsa", "RS256")
+	other := newTestKey(t, "other", "ES256")
+	e := newTestJWTExtractor(t, JWTConfig{JWKSURL: newJWKSServer(t, key, newTestKey(t, "ec", "ES256")).URL})
+
+	parts := strings.Split(key.token(t, validClaims()), ".")
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "alg none", token: noneHeader + "." + parts[1] + "."},
+		{name: "empty signature", token: parts[0] + "." + parts[1] + "."},
+		{name: "algorithm of another key type", token: key.sign(t, map[string]interface{}{"alg": "ES256", "kid": "rsa"}, validClaims())},
+		{name: "known kid signed by another key", token: other.sign(t, map[string]interface{}{"alg": "ES256", "kid": "ec"}, validClaims())},
+		{name: "unknown kid", token: other.token(t, validClaims())},
+		{name: "malformed", token: "not.a.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := e.Verify(context.Background(), tt.token); !errors.Is(err, ErrTokenInvalid) {
+				t.Fatalf("err %v, want ErrTokenInvalid", err)
+			}
+		})
+	}
+}
+
+func TestJWTExtractorClaims(t *testing.T) {
+	key := newTestKey(t, "ed", "EdDSA")
+	e := newTestJWTExtractor(t, JWTConfig{
+		JWKSURL:  newJWKSServer(t, key).URL,
+		Issuer:   "https://auth.example.com/",
+		Audience: []string{"locator", "reports"},
+	})
+
+	now := time.Now()
+	claims := func(mutate func(claims map[string]interface{})) map[string]interface{} {
+		c := validClaims()
+		c["iss"] = "https://auth.example.com/"
+		c["aud"] = "locator"
+		mutate(c)
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		claims  map[string]interface{}
+		wantErr error
+	}{
+		{name: "valid", claims: claims(func(c map[string]interface{}) {})},
+		{name: "audience list", claims: claims(func(c map[string]interface{}) { c["aud"] = []string{"billing", "reports"} })},
+		{name: "expired within leeway", claims: claims(func(c map[string]interface{}) { c["exp"] = now.Add(-10 * time.Second).Unix() })},
+		{name: "expired", claims: claims(func(c map[string]interface{}) { c["exp"] = now.Add(-time.Minute).Unix() }), wantErr: ErrTokenExpired},
+		{name: "missing exp", claims: claims(func(c map[string]interface{}) { delete(c, "exp") }), wantErr: ErrTokenInvalid},
+		{name: "not before within leeway", claims: claims(func(c map[string]interface{}) { c["nbf"] = now.Add(10 * time.Second).Unix() })},
+		{name: "not valid yet", claims: claims(func(c map[string]interface{}) { c["nbf"] = now.Add(time.Minute).Unix() }), wantErr: ErrTokenInvalid},
+		{name: "wrong issuer", claims: claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example.com/" }), wantErr: ErrTokenInvalid},
+		{name: "missing issuer", claims: claims(func(c map[string]interface{}) { delete(c, "iss") }), wantErr: ErrTokenInvalid},
+		{name: "wrong audience", claims: claims(func(c map[string]interface{}) { c["aud"] = []string{"billing"} }), wantErr: ErrTokenInvalid},
+		{name: "missing audience", claims: claims(func(c map[string]interface{}) { delete(c, "aud") }), wantErr: ErrTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.Verify(context.Background(), key.token(t, tt.claims))
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWTExtractorRefreshesOnUnknownKey(t *testing.T) {
+	oldKey := newTestKey(t, "2025", "ES256")
+	newKey := newTestKey(t, "2026", "ES256")
+	jwks := newJWKSServer(t, oldKey)
+	e := newTestJWTExtractor(t, JWTConfig{JWKSURL: jwks.URL})
+
+	// Поставщик сменил ключи, новый токен подписан неизвестным ключом
+	jwks.setKeys(oldKey, newKey)
+	token := newKey.token(t, validClaims())
+
+	// Сразу после загрузки набор не обновляется повторно
+	if _, err := e.Verify(context.Background(), token); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("err %v, want ErrTokenInvalid before refresh is allowed", err)
+	}
+	if n := jwks.requestCount(); n != 1 {
+		t.Fatalf("JWKS requested %d times, want 1", n)
+	}
+
+	e.mu.Lock()
+	e.lastRefresh = time.Now().Add(-2 * minJWKSRefreshOnUnknownID)
+	e.mu.Unlock()
+
+	if _, err := e.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify after refresh: %v", err)
+	}
+	if n := jwks.requestCount(); n != 2 {
+		t.Fatalf("JWKS requested %d times, want 2", n)
+	}
+
+	// Неизвестный ключ сразу после обновления не вызывает нового запроса
+	if _, err := e.Verify(context.Background(), newTestKey(t, "forged", "ES256").token(t, validClaims())); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("err %v, want ErrTokenInvalid", err)
+	}
+	if n := jwks.requestCount(); n != 2 {
+		t.Fatalf("JWKS requested %d times, want 2", n)
+	}
+}
+
+func TestJWTExtractorThrottlesFailedRefresh(t *testing.T) {
+	key := newTestKey(t, "2025", "ES256")
+	jwks := newJWKSServer(t, key)
+	e := newTestJWTExtractor(t, JWTConfig{JWKSURL: jwks.URL})
+
+	jwks.setFailing(true)
+	e.mu.Lock()
+	e.lastRefresh = time.Now().Add(-2 * minJWKSRefreshOnUnknownID)
+	e.mu.Unlock()
+
+	// Параллельные запросы с неизвестным ключом вызывают одну попытку загрузки
+	token := newTestKey(t, "unknown", "ES256").token(t, validClaims())
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := e.Verify(context.Background(), token); err == nil {
+				t.Error("Verify accepted a token signed by an unknown key")
+			}
+		}()
+	}
+	wg.Wait()
+	if n := jwks.requestCount(); n != 2 {
+		t.Fatalf("JWKS requested %d times, want 2", n)
+	}
+
+	// Неудачная попытка тоже ограничивает частоту обновлений
+	for i := 0; i < 5; i++ {
+		if _, err := e.Verify(context.Background(), token); !errors.Is(err, ErrTokenInvalid) {
+			t.Fatalf("err %v, want ErrTokenInvalid", err)
+		}
+	}
+	if n := jwks.requestCount(); n != 2 {
+		t.Fatalf("JWKS requested %d times after a failed refresh, want 2", n)
+	}
+
+	// Ранее загруженные ключи продолжают работать
+	if _, err := e.Verify(context.Background(), key.token(t, validClaims())); err != nil {
+		t.Fatalf("Verify with a known key: %v", err)
+	}
+}
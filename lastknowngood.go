@@ -0,0 +1,212 @@
+package locatorars
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultLastKnownGoodGraceWindow = 5 * time.Minute
+	defaultLastKnownGoodMaxEntries  = 10000
+)
+
+// LastKnownGoodConfig определяет параметры хранилища последних подтвержденных решений.
+// Пока сервис locator-ars недоступен, AccessClient применяет решения, полученные от него
+// не раньше GraceWindow назад, вместо политики AllowOnFailure
+type LastKnownGoodConfig struct {
+	// Включает хранилище последних подтвержденных решений
+	Enabled bool
+
+	// Сколько времени после получения решение может применяться при недоступности сервиса
+	// По умолчанию: 5 минут
+	GraceWindow time.Duration
+
+	// Максимальное количество хранимых решений (LRU)
+	// По умолчанию: 10000
+	MaxEntries int
+}
+
+// staleRequest действие и Entitlements решения, выданного из хранилища во время сбоя
+type staleRequest struct {
+	action       string
+	entitlements string
+}
+
+// lastKnownGood хранилище последних успешных решений сервиса
+type lastKnownGood struct {
+	decisions   *decisionCache
+	graceWindow time.Duration
+
+	// outage устанавливается при первой ошибке сервиса и сбрасывается
+	// при первом успешном ответе. Во время сбоя известные решения выдаются
+	// сразу, а доступность сервиса проверяется одним фоновым запросом
+	outage  atomic.Bool
+	probing atomic.Bool
+
+	mu sync.Mutex
+	// served решения, выданные во время сбоя. После восстановления сервиса они обновляются в фоне
+	served map[string]staleRequest
+}
+
+// newLastKnownGood создает хранилище, подставляя значения по умолчанию
+func newLastKnownGood(config LastKnownGoodConfig) *lastKnownGood {
+	if config.GraceWindow <= 0 {
+		config.GraceWindow = defaultLastKnownGoodGraceWindow
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultLastKnownGoodMaxEntries
+	}
+
+	return &lastKnownGood{
+		decisions: newDecisionCache(CacheConfig{
+			AllowTTL:   config.GraceWindow,
+			DenyTTL:    config.GraceWindow,
+			MaxEntries: config.MaxEntries,
+		}),
+		graceWindow: config.GraceWindow,
+		served:      make(map[string]staleRequest),
+	}
+}
+
+// remember сохраняет копию решения сервиса на GraceWindow с момента его получения.
+// Решение из кэша или хранилища не продлевает срок и не заменяет более новое
+func (l *lastKnownGood) remember(key string, decision AccessResponse) {
+	expiresAt := decision.confirmed().Add(l.graceWindow)
+	if _, current, ok := l.decisions.lookup(key); ok && current.After(expiresAt) {
+		return
+	}
+	decision.User = maps.Clone(decision.User)
+	l.decisions.setUntil(key, decision, expiresAt)
+}
+
+// lookup возвращает копию сохраненного решения и время, прошедшее с его получения
+func (l *lastKnownGood) lookup(key string) (AccessResponse, time.Duration, bool) {
+	decision, expiresAt, ok := l.decisions.lookup(key)
+	if !ok {
+		return AccessResponse{}, 0, false
+	}
+	decision.User = maps.Clone(decision.User)
+	return decision, l.graceWindow - time.Until(expiresAt), true
+}
+
+// rememberLastKnownGood сохраняет успешное решение сервиса. Если до этого сервис
+// был недоступен, решения, выданные во время сбоя, обновляются в фоне
+func (ac *AccessClient) rememberLastKnownGood(key string, decision AccessResponse) {
+	if ac.lkg == nil {
+		return
+	}
+	ac.lkg.remember(key, decision)
+
+	if ac.lkg.outage.CompareAndSwap(true, false) {
+		go ac.refreshServed()
+	}
+}
+
+// lastKnownGoodDuringOutage выдает сохраненное решение без обращения к сервису,
+// если сервис недоступен, и запускает фоновую проверку его доступности
+func (ac *AccessClient) lastKnownGoodDuringOutage(key, action, entitlements string) (*AccessResponse, bool) {
+	if ac.lkg == nil || !ac.lkg.outage.Load() {
+		return nil, false
+	}
+
+	decision, ok := ac.serveLastKnownGood(key, action, entitlements, nil)
+	if ok && ac.lkg.probing.CompareAndSwap(false, true) {
+		go ac.probe(key, action, entitlements)
+	}
+	return decision, ok
+}
+
+// lastKnownGoodOnError выдает сохраненное решение вместо ошибки сервиса cause.
+// Сбоем считаются только недоступность сервиса и таймаут (ErrUnavailable, ErrTimeout):
+// ответ 4xx, некорректный ответ или отмена запроса вызывающей стороной возвращаются как есть
+func (ac *AccessClient) lastKnownGoodOnError(ctx context.Context, key, action, entitlements string, cause error) (*AccessResponse, bool) {
+	if ac.lkg == nil || ctx.Err() != nil || ac.initErr != nil || !isServiceFailure(cause) {
+		return nil, false
+	}
+
+	if ac.lkg.outage.CompareAndSwap(false, true) {
+		ac.logger.Error("Access service is unavailable, serving last known good decisions for up to %v: %v", ac.lkg.graceWindow, cause)
+	}
+	return ac.serveLastKnownGood(key, action, entitlements, cause)
+}
+
+// serveLastKnownGood возвращает сохраненное решение и логирует его выдачу
+func (ac *AccessClient) serveLastKnownGood(key, action, entitlements string, cause error) (*AccessResponse, bool) {
+	decision, age, ok := ac.lkg.lookup(key)
+	if !ok {
+		return nil, false
+	}
+
+	ac.lkg.mu.Lock()
+	ac.lkg.served[key] = staleRequest{action: action, entitlements: entitlements}
+	ac.lkg.mu.Unlock()
+
+	if cause != nil {
+		ac.logger.Info("Serving last known good decision for action %s confirmed %v ago: allowed=%v, error: %v",
+			action, age.Round(time.Millisecond), decision.Allowed, cause)
+	} else {
+		ac.logger.Info("Serving last known good decision for action %s confirmed %v ago: allowed=%v",
+			action, age.Round(time.Millisecond), decision.Allowed)
+	}
+	return &decision, true
+}
+
+// probe проверяет доступность сервиса фоновым запросом во время сбоя
+func (ac *AccessClient) probe(key, action, entitlements string) {
+	defer ac.lkg.probing.Store(false)
+
+	ctx, cancel := ac.backgroundContext()
+	defer cancel()
+
+	if decision, err := ac.fetchDecision(ctx, action, entitlements); err == nil {
+		ac.storeDecision(key, *decision)
+	}
+}
+
+// refreshServed обновляет решения, выданные из хранилища во время сбоя.
+// Решения запрашиваются последовательно, чтобы не нагружать восстановившийся сервис
+func (ac *AccessClient) refreshServed() {
+	ac.lkg.mu.Lock()
+	served := ac.lkg.served
+	ac.lkg.served = make(map[string]staleRequest)
+	ac.lkg.mu.Unlock()
+
+	if len(served) == 0 {
+		ac.logger.Info("Access service recovered")
+		return
+	}
+	ac.logger.Info("Access service recovered, refreshing %d last known good decisions", len(served))
+
+	for key, request := range served {
+		ctx, cancel := ac.backgroundContext()
+		decision, err := ac.fetchDecision(ctx, request.action, request.entitlements)
+		cancel()
+		if err != nil {
+			ac.logger.Error("Failed to refresh last known good decision for action %s: %v", request.action, err)
+			continue
+		}
+		ac.storeDecision(key, *decision)
+	}
+}
+
+// storeDecision сохраняет решение сервиса в кэше и хранилище последних подтвержденных решений
+func (ac *AccessClient) storeDecision(key string, decision AccessResponse) {
+	if ac.cache != nil {
+		cached := decision
+		cached.User = maps.Clone(decision.User)
+		ac.cache.set(key, cached)
+	}
+	ac.rememberLastKnownGood(key, decision)
+}
+
+// backgroundContext возвращает контекст фонового запроса, ограниченный таймаутом запроса к сервису
+func (ac *AccessClient) backgroundContext() (context.Context, context.CancelFunc) {
+	timeout := ac.config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
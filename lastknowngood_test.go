@@ -0,0 +1,70 @@
+package locatorars_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+func newLastKnownGoodClient(s *locatorarstest.Server) *locatorars.AccessClient {
+	config := s.Config()
+	config.LastKnownGood = locatorars.LastKnownGoodConfig{Enabled: true}
+	return locatorars.NewAccessClient(config)
+}
+
+func TestLastKnownGoodServedWhenServiceUnavailable(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	client := newLastKnownGoodClient(s)
+	ctx := context.Background()
+
+	s.Allow("reports.view", "reports.view")
+	if result := client.Authorize(ctx, "reports.view", "reports.view"); result.Outcome != locatorars.OutcomeAllowed {
+		t.Fatalf("first check: outcome %v, err %v", result.Outcome, result.Err)
+	}
+
+	s.Reset()
+	s.Fail("reports.view", http.StatusServiceUnavailable)
+	allowed, err := client.CheckAccessContext(ctx, "reports.view", "reports.view")
+	if err != nil || !allowed {
+		t.Fatalf("during outage: allowed %v, err %v, want last known good allow", allowed, err)
+	}
+}
+
+func TestLastKnownGoodNotServedForClientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusForbidden} {
+		s := locatorarstest.NewServer(t)
+		client := newLastKnownGoodClient(s)
+		ctx := context.Background()
+
+		s.Allow("reports.view", "reports.view")
+		if allowed, err := client.CheckAccessContext(ctx, "reports.view", "reports.view"); err != nil || !allowed {
+			t.Fatalf("first check: allowed %v, err %v", allowed, err)
+		}
+
+		s.Reset()
+		s.Fail("reports.view", status)
+		allowed, err := client.CheckAccessContext(ctx, "reports.view", "reports.view")
+		if allowed || err == nil {
+			t.Fatalf("status %d: allowed %v, err %v, want error", status, allowed, err)
+		}
+		var statusErr *locatorars.StatusError
+		if !errors.As(err, &statusErr) || statusErr.Code != status {
+			t.Fatalf("status %d: err %v, want StatusError", status, err)
+		}
+
+		if result := client.Authorize(ctx, "reports.view", "reports.view"); result.Outcome != locatorars.OutcomeError {
+			t.Fatalf("status %d: outcome %v, want %v", status, result.Outcome, locatorars.OutcomeError)
+		}
+
+		// Ответ 4xx не переводит клиент в режим сбоя
+		s.Reset()
+		s.Deny("reports.view")
+		if result := client.Authorize(ctx, "reports.view", "reports.view"); result.Outcome != locatorars.OutcomeDenied {
+			t.Fatalf("status %d: after recovery outcome %v, want %v", status, result.Outcome, locatorars.OutcomeDenied)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package locatorars
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultEnvPrefix префикс переменных окружения ConfigFromEnv по умолчанию
+const DefaultEnvPrefix = "LOCATOR_ARS"
+
+// configField параметр конфигурации, который можно задать строкой
+// в переменной окружения или в файле
+type configField struct {
+	// Имя параметра в файле, вложенные параметры через точку, например "cache.allow_ttl".
+	// Имя переменной окружения получается заменой точек на "_" и приведением к верхнему регистру
+	name string
+	set  func(c *Config, value string) error
+}
+
+// configFields параметры, поддерживаемые ConfigFromEnv и ConfigFromFile
+var configFields = []configField{
+	stringField("url", func(c *Config) *string { return &c.URL }),
+	boolField("allow_on_failure", func(c *Config) *bool { return &c.AllowOnFailure }),
+	{name: "log_level", set: func(c *Config, value string) error {
+		level, err := ParseLogLevel(value)
+		c.LogLevel = level
+		return err
+	}},
+	durationField("timeout", func(c *Config) *time.Duration { return &c.Timeout }),
+	stringField("batch_url", func(c *Config) *string { return &c.BatchURL }),
+	intField("batch_concurrency", func(c *Config) *int { return &c.BatchConcurrency }),
+	{name: "mode", set: func(c *Config, value string) error {
+		c.Mode = EvaluationMode(strings.ToLower(value))
+		return nil
+	}},
+	stringField("local_policy_file", func(c *Config) *string { return &c.LocalPolicyFile }),
+	boolField("disable_coalescing", func(c *Config) *bool { return &c.DisableCoalescing }),
+
+	boolField("cache.enabled", func(c *Config) *bool { return &c.Cache.Enabled }),
+	durationField("cache.allow_ttl", func(c *Config) *time.Duration { return &c.Cache.AllowTTL }),
+	durationField("cache.deny_ttl", func(c *Config) *time.Duration { return &c.Cache.DenyTTL }),
+	intField("cache.max_entries", func(c *Config) *int { return &c.Cache.MaxEntries }),
+
+	intField("retry.max_attempts", func(c *Config) *int { return &c.Retry.MaxAttempts }),
+	durationField("retry.initial_backoff", func(c *Config) *time.Duration { return &c.Retry.InitialBackoff }),
+	durationField("retry.max_backoff", func(c *Config) *time.Duration { return &c.Retry.MaxBackoff }),
+	floatField("retry.multiplier", func(c *Config) *float64 { return &c.Retry.Multiplier }),
+
+	boolField("circuit_breaker.enabled", func(c *Config) *bool { return &c.CircuitBreaker.Enabled }),
+	intField("circuit_breaker.failure_threshold", func(c *Config) *int { return &c.CircuitBreaker.FailureThreshold }),
+	durationField("circuit_breaker.cool_down", func(c *Config) *time.Duration { return &c.CircuitBreaker.CoolDown }),
+	intField("circuit_breaker.half_open_max_requests", func(c *Config) *int { return &c.CircuitBreaker.HalfOpenMaxRequests }),
+
+	boolField("last_known_good.enabled", func(c *Config) *bool { return &c.LastKnownGood.Enabled }),
+	durationField("last_known_good.grace_window", func(c *Config) *time.Duration { return &c.LastKnownGood.GraceWindow }),
+	intField("last_known_good.max_entries", func(c *Config) *int { return &c.LastKnownGood.MaxEntries }),
+
+	stringField("tls.ca_file", func(c *Config) *string { return &c.TLS.CAFile }),
+	stringField("tls.cert_file", func(c *Config) *string { return &c.TLS.CertFile }),
+	stringField("tls.key_file", func(c *Config) *string { return &c.TLS.KeyFile }),
+	stringField("tls.server_name", func(c *Config) *string { return &c.TLS.ServerName }),
+}
+
+// ConfigFromEnv возвращает конфигурацию по умолчанию, дополненную переменными окружения
+// с префиксом prefix (по умолчанию "LOCATOR_ARS"): LOCATOR_ARS_URL, LOCATOR_ARS_ALLOW_ON_FAILURE,
+// LOCATOR_ARS_LOG_LEVEL, LOCATOR_ARS_TIMEOUT, LOCATOR_ARS_CACHE_ENABLED и т.д.
+// Ошибки разбора и проверки конфигурации возвращаются вместе
+func ConfigFromEnv(prefix string) (Config, error) {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	prefix = strings.TrimSuffix(prefix, "_") + "_"
+
+	config := DefaultConfig()
+	var errs []error
+	for _, field := range configFields {
+		name := prefix + strings.ToUpper(strings.ReplaceAll(field.name, ".", "_"))
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := field.set(&config, strings.TrimSpace(value)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	errs = append(errs, config.Validate())
+	return config, errors.Join(errs...)
+}
+
+// ConfigFromFile возвращает конфигурацию по умолчанию, дополненную параметрами из файла
+// YAML (.yaml, .yml), JSON (.json) или TOML (.toml):
+//
+//	url: http://locator-ars:9012/api/v1/ars/check
+//	allow_on_failure: false
+//	log_level: info
+//	timeout: 3s
+//	cache:
+//	  enabled: true
+//	  allow_ttl: 1m
+//
+// Неизвестные параметры считаются ошибкой. Ошибки разбора и проверки конфигурации возвращаются вместе
+func ConfigFromFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return Config{}, fmt.Errorf("unsupported config format %q, expected .yaml, .yml, .json or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	var errs []error
+	flattenConfig("", raw, values, &errs)
+
+	fields := make(map[string]configField, len(configFields))
+	for _, field := range configFields {
+		fields[field.name] = field
+	}
+
+	// Сортируем ключи, чтобы порядок ошибок не зависел от обхода карты
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	config := DefaultConfig()
+	for _, key := range keys {
+		field, ok := fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown config key %q", key))
+			continue
+		}
+		if err := field.set(&config, values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	errs = append(errs, config.Validate())
+	return config, errors.Join(errs...)
+}
+
+// flattenConfig преобразует вложенные параметры файла в плоскую карту с ключами через точку
+func flattenConfig(prefix string, raw map[string]interface{}, values map[string]string, errs *[]error) {
+	for key, value := range raw {
+		name := strings.ToLower(key)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenConfig(name, v, values, errs)
+		case []interface{}:
+			*errs = append(*errs, fmt.Errorf("%s: lists are not supported", name))
+		case nil:
+		case float64:
+			// Числа в JSON и дробные числа в YAML и TOML. fmt.Sprint записал бы 1000000 как 1e+06
+			values[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+}
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки
+func (c Config) Validate() error {
+	return c.validate(true)
+}
+
+// validate реализует Validate. Без checkURLs адреса сервиса не проверяются:
+// middleware с собственным checker к сервису не обращается
+func (c Config) validate(checkURLs bool) error {
+	var errs []error
+
+	if checkURLs {
+		if err := validateURL(c.URL); err != nil {
+			errs = append(errs, fmt.Errorf("url: %w", err))
+		}
+		if c.BatchURL != "" {
+			if err := validateURL(c.BatchURL); err != nil {
+				errs = append(errs, fmt.Errorf("batch_url: %w", err))
+			}
+		}
+	}
+	if c.LogLevel < LogLevelNone || c.LogLevel > LogLevelDebug {
+		errs = append(errs, fmt.Errorf("log_level: unknown log level %d", c.LogLevel))
+	}
+	if c.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout: must not be negative, got %v", c.Timeout))
+	}
+	if c.BatchConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("batch_concurrency: must not be negative, got %d", c.BatchConcurrency))
+	}
+
+	switch c.Mode {
+	case "", ModeRemote, ModeLocal, ModeLocalFirst:
+	default:
+		errs = append(errs, fmt.Errorf("mode: unknown evaluation mode %q", c.Mode))
+	}
+	if (c.Mode == ModeLocal || c.Mode == ModeLocalFirst) && c.LocalPolicyFile == "" && c.LocalEvaluator == nil {
+		errs = append(errs, fmt.Errorf("local_policy_file: required for mode %q", c.Mode))
+	}
+
+	if c.Cache.MaxEntries < 0 {
+		errs = append(errs, fmt.Errorf("cache.max_entries: must not be negative, got %d", c.Cache.MaxEntries))
+	}
+
+	if c.Retry.MaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("retry.max_attempts: must not be negative, got %d", c.Retry.MaxAttempts))
+	}
+	if c.Retry.InitialBackoff < 0 || c.Retry.MaxBackoff < 0 {
+		errs = append(errs, fmt.Errorf("retry: backoff must not be negative"))
+	}
+	if c.Retry.Multiplier < 0 {
+		errs = append(errs, fmt.Errorf("retry.multiplier: must not be negative, got %v", c.Retry.Multiplier))
+	}
+
+	if c.CircuitBreaker.FailureThreshold < 0 || c.CircuitBreaker.HalfOpenMaxRequests < 0 || c.CircuitBreaker.CoolDown < 0 {
+		errs = append(errs, fmt.Errorf("circuit_breaker: thresholds and cool down must not be negative"))
+	}
+
+	if c.LastKnownGood.GraceWindow < 0 || c.LastKnownGood.MaxEntries < 0 {
+		errs = append(errs, fmt.Errorf("last_known_good: grace window and max entries must not be negative"))
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("tls: cert_file and key_file must be set together"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateURL проверяет, что value является абсолютным HTTP(S) URL
+func validateURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("malformed URL %q: %w", value, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("malformed URL %q: expected absolute http or https URL", value)
+	}
+	return nil
+}
+
+// ParseLogLevel разбирает уровень логирования: none, error, info или debug
+func ParseLogLevel(value string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "none", "off":
+		return LogLevelNone, nil
+	case "error":
+		return LogLevelError, nil
+	case "info":
+		return LogLevelInfo, nil
+	case "debug":
+		return LogLevelDebug, nil
+	default:
+		return LogLevelError, fmt.Errorf("unknown log level %q, expected none, error, info or debug", value)
+	}
+}
+
+func stringField(name string, field func(c *Config) *string) configField {
+	return configField{name: name, set: func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}}
+}
+
+func boolField(name string, field func(c *Config) *bool) configField {
+	return configField{name: name, set: func(c *Config, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}}
+}
+
+func intField(name string, field func(c *Config) *int) configField {
+	return configField{name: name, set: func(c *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}}
+}
+
+func floatField(name string, field func(c *Config) *float64) configField {
+	return configField{name: name, set: func(c *Config, value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}}
+}
+
+func durationField(name string, field func(c *Config) *time.Duration) configField {
+	return configField{name: name, set: func(c *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected a value like 5s or 250ms", value)
+		}
+		*field(c) = parsed
+		return nil
+	}}
+}
@@ -0,0 +1,266 @@
+package locatorars
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile записывает файл конфигурации во временный каталог теста
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkErrors проверяет, что err содержит все ожидаемые фрагменты
+func checkErrors(t *testing.T, err error, want ...string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("no error, want %q", want)
+	}
+	for _, fragment := range want {
+		if !strings.Contains(err.Error(), fragment) {
+			t.Errorf("error %q does not contain %q", err, fragment)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("APP_URL", "https://ars.example.com/api/v1/ars/check")
+	t.Setenv("APP_ALLOW_ON_FAILURE", "true")
+	t.Setenv("APP_LOG_LEVEL", " debug ")
+	t.Setenv("APP_TIMEOUT", "250ms")
+	t.Setenv("APP_CACHE_ENABLED", "1")
+	t.Setenv("APP_CACHE_ALLOW_TTL", "1m30s")
+	t.Setenv("APP_RETRY_MULTIPLIER", "1.5")
+	t.Setenv("APP_MODE", "Local-First")
+	t.Setenv("APP_LOCAL_POLICY_FILE", "policy.yaml")
+
+	// Префикс указывается с завершающим "_" или без него
+	for _, prefix := range []string{"APP", "APP_"} {
+		config, err := ConfigFromEnv(prefix)
+		if err != nil {
+			t.Fatalf("ConfigFromEnv(%q): %v", prefix, err)
+		}
+		if config.URL != "https://ars.example.com/api/v1/ars/check" || !config.AllowOnFailure || config.LogLevel != LogLevelDebug {
+			t.Errorf("config %+v", config)
+		}
+		if config.Timeout != 250*time.Millisecond || !config.Cache.Enabled || config.Cache.AllowTTL != 90*time.Second {
+			t.Errorf("timeout %v, cache %+v", config.Timeout, config.Cache)
+		}
+		if config.Retry.Multiplier != 1.5 || config.Mode != ModeLocalFirst {
+			t.Errorf("retry multiplier %v, mode %q", config.Retry.Multiplier, config.Mode)
+		}
+		// Незаданные параметры сохраняют значения по умолчанию
+		if config.Cache.DenyTTL != defaultCacheDenyTTL || config.Retry.MaxAttempts != 1 {
+			t.Errorf("defaults are not kept: deny TTL %v, max attempts %d", config.Cache.DenyTTL, config.Retry.MaxAttempts)
+		}
+	}
+}
+
+func TestConfigFromEnvDefaultPrefix(t *testing.T) {
+	t.Setenv("LOCATOR_ARS_TIMEOUT", "2s")
+
+	config, err := ConfigFromEnv("")
+	if err != nil {
+		t.Fatalf("ConfigFromEnv: %v", err)
+	}
+	if config.Timeout != 2*time.Second || config.URL != DefaultConfig().URL {
+		t.Errorf("timeout %v, url %q", config.Timeout, config.URL)
+	}
+}
+
+func TestConfigFromEnvErrors(t *testing.T) {
+	t.Setenv("APP_URL", "locator-ars:9012")
+	t.Setenv("APP_ALLOW_ON_FAILURE", "maybe")
+	t.Setenv("APP_LOG_LEVEL", "verbose")
+	t.Setenv("APP_TIMEOUT", "5")
+	t.Setenv("APP_CACHE_MAX_ENTRIES", "-1")
+
+	// Все ошибки разбора и проверки возвращаются вместе
+	_, err := ConfigFromEnv("APP")
+	checkErrors(t, err,
+		`APP_ALLOW_ON_FAILURE: invalid boolean "maybe"`,
+		`APP_LOG_LEVEL: unknown log level "verbose"`,
+		`APP_TIMEOUT: invalid duration "5", expected a value like 5s or 250ms`,
+		`url: malformed URL "locator-ars:9012"`,
+		"cache.max_entries: must not be negative, got -1",
+	)
+}
+
+func TestConfigFromFile(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+url: https://ars.example.com/api/v1/ars/check
+allow_on_failure: true
+log_level: info
+timeout: 3s
+cache:
+  enabled: true
+  allow_ttl: 1m
+  max_entries: 1000000
+retry:
+  max_attempts: 3
+  multiplier: 1.5
+`,
+		"config.json": `{
+	"url": "https://ars.example.com/api/v1/ars/check",
+	"allow_on_failure": true,
+	"log_level": "info",
+	"timeout": "3s",
+	"cache": {"enabled": true, "allow_ttl": "1m", "max_entries": 1000000},
+	"retry": {"max_attempts": 3, "multiplier": 1.5}
+}`,
+		"config.toml": `
+url = "https://ars.example.com/api/v1/ars/check"
+allow_on_failure = true
+log_level = "info"
+timeout = "3s"
+
+[cache]
+enabled = true
+allow_ttl = "1m"
+max_entries = 1000000
+
+[retry]
+max_attempts = 3
+multiplier = 1.5
+`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			config, err := ConfigFromFile(writeConfigFile(t, name, content))
+			if err != nil {
+				t.Fatalf("ConfigFromFile: %v", err)
+			}
+			if config.URL != "https://ars.example.com/api/v1/ars/check" || !config.AllowOnFailure || config.LogLevel != LogLevelInfo {
+				t.Errorf("config %+v", config)
+			}
+			if config.Timeout != 3*time.Second || !config.Cache.Enabled || config.Cache.AllowTTL != time.Minute {
+				t.Errorf("timeout %v, cache %+v", config.Timeout, config.Cache)
+			}
+			// Большие числа JSON разбираются как float64 и не должны записываться с экспонентой
+			if config.Cache.MaxEntries != 1000000 {
+				t.Errorf("cache max entries %d, want 1000000", config.Cache.MaxEntries)
+			}
+			if config.Retry.MaxAttempts != 3 || config.Retry.Multiplier != 1.5 {
+				t.Errorf("retry %+v", config.Retry)
+			}
+		})
+	}
+}
+
+func TestConfigFromFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string
+	}{
+		{
+			name: "aggregated",
+			file: "config.yaml",
+			content: `
+url: ""
+timeout: soon
+retries: 3
+cache:
+  enabled: yes please
+  max_entries: 1.5
+tls:
+  cert_file: client.pem
+  ca_file: [a, b]
+`,
+			want: []string{
+				`cache.enabled: invalid boolean "yes please"`,
+				`cache.max_entries: invalid integer "1.5"`,
+				`unknown config key "retries"`,
+				`timeout: invalid duration "soon"`,
+				"tls.ca_file: lists are not supported",
+				`url: malformed URL ""`,
+				"tls: cert_file and key_file must be set together",
+			},
+		},
+		{name: "unsupported format", file: "config.ini", content: "url=x", want: []string{`unsupported config format ".ini"`}},
+		{name: "malformed", file: "config.json", content: "{", want: []string{"failed to parse config"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ConfigFromFile(writeConfigFile(t, tt.file, tt.content))
+			checkErrors(t, err, tt.want...)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := ConfigFromFile(filepath.Join(t.TempDir(), "missing.yaml"))
+		checkErrors(t, err, "failed to read config")
+	})
+
+	t.Run("errors are sorted by key", func(t *testing.T) {
+		_, err := ConfigFromFile(writeConfigFile(t, "config.json", `{"b_unknown": 1, "a_unknown": 2}`))
+		checkErrors(t, err, `unknown config key "a_unknown"`+"\n"+`unknown config key "b_unknown"`)
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("default config is invalid: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{name: "relative url", modify: func(c *Config) { c.URL = "/api/v1/ars/check" }, want: "url: malformed URL"},
+		{name: "unsupported scheme", modify: func(c *Config) { c.URL = "ftp://ars/check" }, want: "expected absolute http or https URL"},
+		{name: "batch url", modify: func(c *Config) { c.BatchURL = "ars/batch" }, want: "batch_url: malformed URL"},
+		{name: "log level", modify: func(c *Config) { c.LogLevel = LogLevelDebug + 1 }, want: "log_level: unknown log level"},
+		{name: "timeout", modify: func(c *Config) { c.Timeout = -time.Second }, want: "timeout: must not be negative, got -1s"},
+		{name: "batch concurrency", modify: func(c *Config) { c.BatchConcurrency = -1 }, want: "batch_concurrency: must not be negative"},
+		{name: "mode", modify: func(c *Config) { c.Mode = "hybrid" }, want: `mode: unknown evaluation mode "hybrid"`},
+		{name: "local mode without policy", modify: func(c *Config) { c.Mode = ModeLocal }, want: `local_policy_file: required for mode "local"`},
+		{name: "retry attempts", modify: func(c *Config) { c.Retry.MaxAttempts = -1 }, want: "retry.max_attempts: must not be negative"},
+		{name: "retry backoff", modify: func(c *Config) { c.Retry.MaxBackoff = -time.Second }, want: "retry: backoff must not be negative"},
+		{name: "retry multiplier", modify: func(c *Config) { c.Retry.Multiplier = -0.5 }, want: "retry.multiplier: must not be negative, got -0.5"},
+		{name: "circuit breaker", modify: func(c *Config) { c.CircuitBreaker.CoolDown = -time.Second }, want: "circuit_breaker:"},
+		{name: "last known good", modify: func(c *Config) { c.LastKnownGood.MaxEntries = -1 }, want: "last_known_good:"},
+		{name: "tls key without cert", modify: func(c *Config) { c.TLS.KeyFile = "client.key" }, want: "tls: cert_file and key_file must be set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.modify(&config)
+			checkErrors(t, config.Validate(), tt.want)
+		})
+	}
+
+	t.Run("all errors", func(t *testing.T) {
+		config := DefaultConfig()
+		config.URL = ""
+		config.Timeout = -time.Second
+		config.Cache.MaxEntries = -1
+		err := config.Validate()
+		checkErrors(t, err, "url:", "timeout:", "cache.max_entries:")
+		if n := len(strings.Split(err.Error(), "\n")); n != 3 {
+			t.Errorf("%d errors, want 3: %v", n, err)
+		}
+	})
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for value, want := range map[string]LogLevel{"none": LogLevelNone, "OFF": LogLevelNone, "error": LogLevelError, " Info ": LogLevelInfo, "debug": LogLevelDebug} {
+		if got, err := ParseLogLevel(value); err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseLogLevel("trace"); err == nil {
+		t.Error("ParseLogLevel accepted an unknown level")
+	}
+}
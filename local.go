@@ -0,0 +1,157 @@
+package locatorars
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EvaluationMode определяет, где принимается решение о доступе
+type EvaluationMode string
+
+const (
+	// ModeRemote - решение принимает сервис locator-ars (по умолчанию)
+	ModeRemote EvaluationMode = "remote"
+	// ModeLocal - решение принимается только по локальной политике, сервис не используется
+	ModeLocal EvaluationMode = "local"
+	// ModeLocalFirst - действия из локальной политики проверяются локально,
+	// остальные передаются сервису locator-ars
+	ModeLocalFirst EvaluationMode = "local-first"
+)
+
+// localPolicy формат файла локальной политики
+type localPolicy struct {
+	// Действие и список Entitlements, которые все требуются для доступа к нему
+	Actions map[string][]string `json:"actions" yaml:"actions"`
+}
+
+// LocalEvaluator принимает решения о доступе без сервиса locator-ars
+// по локальному сопоставлению действий и требуемых Entitlements.
+// Доступ разрешается, если у запроса есть все Entitlements, требуемые действием.
+// Действия, отсутствующие в политике, запрещены
+type LocalEvaluator struct {
+	actions map[string][]string
+}
+
+// NewLocalEvaluator создает локальный вычислитель из сопоставления
+// действие -> требуемые Entitlements. Каждое действие должно требовать хотя бы одно Entitlement
+func NewLocalEvaluator(actions map[string][]string) (*LocalEvaluator, error) {
+	copied := make(map[string][]string, len(actions))
+	for action, required := range actions {
+		if len(required) == 0 {
+			// Действие без требований было бы доступно любому запросу с Entitlements
+			return nil, fmt.Errorf("action %q has no required entitlements", action)
+		}
+		copied[action] = append([]string(nil), required...)
+	}
+	return &LocalEvaluator{actions: copied}, nil
+}
+
+// LoadLocalEvaluator загружает локальную политику из файла YAML или JSON.
+// Формат определяется по расширению, файлы с расширением .json разбираются как JSON,
+// остальные как YAML:
+//
+//	actions:
+//	  reports.view: [reports]
+//	  reports.edit: [reports, reports-editor]
+func LoadLocalEvaluator(path string) (*LocalEvaluator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local policy: %w", err)
+	}
+
+	var policy localPolicy
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &policy)
+	} else {
+		err = yaml.Unmarshal(data, &policy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse local policy %s: %w", path, err)
+	}
+
+	evaluator, err := NewLocalEvaluator(policy.Actions)
+	if err != nil {
+		return nil, fmt.Errorf("local policy %s: %w", path, err)
+	}
+	return evaluator, nil
+}
+
+// CheckAccessDetailed принимает решение по локальной политике
+func (e *LocalEvaluator) CheckAccessDetailed(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+	if decision, ok := e.decide(action, entitlements); ok {
+		return decision, nil
+	}
+	return &AccessResponse{
+		Action:  action,
+		Allowed: false,
+		Message: "action is not defined in local policy",
+	}, nil
+}
+
+// CheckAccessContext проверяет доступ по локальной политике
+func (e *LocalEvaluator) CheckAccessContext(ctx context.Context, action, entitlements string) (bool, error) {
+	decision, err := e.CheckAccessDetailed(ctx, action, entitlements)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed, nil
+}
+
+// Has возвращает true, если действие описано в локальной политике
+func (e *LocalEvaluator) Has(action string) bool {
+	_, ok := e.actions[action]
+	return ok
+}
+
+// newLocalForMode возвращает локальный вычислитель для режима из конфигурации.
+// В режиме ModeRemote локальная политика не используется
+func newLocalForMode(config Config) (*LocalEvaluator, error) {
+	switch config.Mode {
+	case "", ModeRemote:
+		return nil, nil
+	case ModeLocal, ModeLocalFirst:
+	default:
+		return nil, fmt.Errorf("unknown evaluation mode %q", config.Mode)
+	}
+
+	if config.LocalEvaluator != nil {
+		return config.LocalEvaluator, nil
+	}
+	if config.LocalPolicyFile == "" {
+		return nil, fmt.Errorf("mode %q requires LocalPolicyFile or LocalEvaluator", config.Mode)
+	}
+	return LoadLocalEvaluator(config.LocalPolicyFile)
+}
+
+// decide принимает решение для действия из политики.
+// Возвращает false, если действие в политике не описано
+func (e *LocalEvaluator) decide(action, entitlements string) (*AccessResponse, bool) {
+	required, ok := e.actions[action]
+	if !ok {
+		return nil, false
+	}
+
+	granted := make(map[string]struct{})
+	for _, entitlement := range strings.Split(entitlements, entitlementsSeparator) {
+		if entitlement = strings.TrimSpace(entitlement); entitlement != "" {
+			granted[entitlement] = struct{}{}
+		}
+	}
+
+	for _, entitlement := range required {
+		if _, ok := granted[entitlement]; !ok {
+			return &AccessResponse{
+				Action:  action,
+				Allowed: false,
+				Message: "missing entitlement " + entitlement,
+			}, true
+		}
+	}
+	return &AccessResponse{Action: action, Allowed: true, Message: "allowed by local policy"}, true
+}
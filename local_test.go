@@ -0,0 +1,49 @@
+package locatorars
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewLocalEvaluator(t *testing.T) {
+	evaluator, err := NewLocalEvaluator(map[string][]string{
+		"reports.view": {"reports"},
+		"reports.edit": {"reports", "reports-editor"},
+	})
+	if err != nil {
+		t.Fatalf("NewLocalEvaluator: %v", err)
+	}
+
+	tests := []struct {
+		action       string
+		entitlements string
+		allowed      bool
+	}{
+		{action: "reports.view", entitlements: "reports", allowed: true},
+		{action: "reports.edit", entitlements: "reports", allowed: false},
+		{action: "reports.edit", entitlements: "reports|reports-editor", allowed: true},
+		{action: "reports.delete", entitlements: "reports|reports-editor", allowed: false},
+	}
+	for _, tt := range tests {
+		allowed, err := evaluator.CheckAccessContext(context.Background(), tt.action, tt.entitlements)
+		if err != nil || allowed != tt.allowed {
+			t.Errorf("%s with %q: allowed %v, err %v, want %v", tt.action, tt.entitlements, allowed, err, tt.allowed)
+		}
+	}
+}
+
+func TestLocalEvaluatorRejectsEmptyRequirements(t *testing.T) {
+	if _, err := NewLocalEvaluator(map[string][]string{"reports.view": {}}); err == nil {
+		t.Error("NewLocalEvaluator accepted an action without required entitlements")
+	}
+
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("actions:\n  reports.view: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLocalEvaluator(path); err == nil {
+		t.Error("LoadLocalEvaluator accepted an action without required entitlements")
+	}
+}
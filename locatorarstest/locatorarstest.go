@@ -0,0 +1,355 @@
+// Package locatorarstest предоставляет фейковый сервис locator-ars для тестов
+// маршрутов, защищенных middleware locatorars.
+//
+// Фейк отвечает по программируемым правилам для действий и Entitlements,
+// умеет добавлять задержку и внедрять ошибки, записывает полученные запросы
+// и возвращает готовую Config, указывающую на себя:
+//
+//	func TestReports(t *testing.T) {
+//		ars := locatorarstest.NewServer(t)
+//		ars.Allow("reports.view", "reports")
+//
+//		m := locatorars.NewMiddleware(ars.Config())
+//		// ... маршрут с m.RequireActionHTTP("reports.view")
+//
+//		if ars.Calls("reports.view") != 1 {
+//			t.Fatal("expected one access check")
+//		}
+//	}
+package locatorarstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+)
+
+// Пути эндпоинтов фейкового сервиса
+const (
+	CheckPath = "/api/v1/ars/check"
+	BatchPath = "/api/v1/ars/check/batch"
+)
+
+// Rule правило ответа фейкового сервиса. Правила проверяются в порядке добавления,
+// применяется первое подходящее. Если подходящего правила нет, доступ запрещается
+type Rule struct {
+	// Действие, к которому применяется правило, пустая строка подходит для любого действия
+	Action string
+
+	// Entitlements, которые все должны быть в запросе, чтобы правило подошло
+	Entitlements []string
+
+	// Решение о доступе
+	Allowed bool
+
+	// Поля ответа сервиса
+	Entity  string
+	Message string
+	User    map[string]interface{}
+
+	// Задержка перед ответом. Прерывается отменой запроса клиентом
+	Latency time.Duration
+
+	// HTTP-статус ответа вместо 200, например 503 для проверки повторов
+	Status int
+
+	// Тело ответа вместо JSON-решения, например некорректный JSON
+	Body string
+
+	// Закрыть соединение без ответа (ошибка транспорта)
+	CloseConnection bool
+
+	// Сколько раз применяется правило, 0 - без ограничения.
+	// Позволяет задать сценарий, например две ошибки 503, затем разрешение
+	Times int
+}
+
+// Request запрос, полученный фейковым сервисом
+type Request struct {
+	// Проверяемое действие
+	Action string
+
+	// Значение заголовка X-Authentik-Entitlements
+	Entitlements string
+
+	// Признак пакетного запроса
+	Batch bool
+
+	// Заголовки запроса
+	Header http.Header
+}
+
+// Server фейковый сервис locator-ars
+type Server struct {
+	// URL запущенного сервера
+	URL string
+
+	server *httptest.Server
+
+	mu       sync.Mutex
+	rules    []*Rule
+	requests []Request
+}
+
+// NewServer запускает фейковый сервис. Сервер останавливается при завершении теста
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc(CheckPath, s.handleCheck)
+	mux.HandleFunc(BatchPath, s.handleBatch)
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Close останавливает сервер
+func (s *Server) Close() {
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// Config возвращает конфигурацию по умолчанию, указывающую на фейковый сервис,
+// с отключенным логированием
+func (s *Server) Config() locatorars.Config {
+	config := locatorars.DefaultConfig()
+	config.URL = s.URL + CheckPath
+	config.BatchURL = s.URL + BatchPath
+	config.LogLevel = locatorars.LogLevelNone
+	return config
+}
+
+// Middleware возвращает middleware с конфигурацией Config
+func (s *Server) Middleware() *locatorars.Middleware {
+	return locatorars.NewMiddleware(s.Config())
+}
+
+// AddRule добавляет правило ответа
+func (s *Server) AddRule(rule Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, &rule)
+}
+
+// Allow разрешает действие запросам, у которых есть все указанные Entitlements
+// (любым запросам, если Entitlements не указаны)
+func (s *Server) Allow(action string, entitlements ...string) {
+	s.AddRule(Rule{Action: action, Entitlements: entitlements, Allowed: true})
+}
+
+// AllowUser разрешает действие и возвращает в ответе пользователя user
+func (s *Server) AllowUser(action string, user map[string]interface{}, entitlements ...string) {
+	s.AddRule(Rule{Action: action, Entitlements: entitlements, Allowed: true, User: user})
+}
+
+// Deny явно запрещает действие запросам, у которых есть все указанные Entitlements
+func (s *Server) Deny(action string, entitlements ...string) {
+	s.AddRule(Rule{Action: action, Entitlements: entitlements, Allowed: false})
+}
+
+// Fail отвечает на проверку действия HTTP-статусом status
+func (s *Server) Fail(action string, status int) {
+	s.AddRule(Rule{Action: action, Status: status})
+}
+
+// Requests возвращает копию списка полученных запросов
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Calls возвращает количество проверок действия, включая действия из пакетных запросов
+func (s *Server) Calls(action string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calls := 0
+	for _, r := range s.requests {
+		if r.Action == action {
+			calls++
+		}
+	}
+	return calls
+}
+
+// ResetRequests удаляет записанные запросы, сохраняя правила
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// Reset удаляет правила и записанные запросы
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = nil
+	s.requests = nil
+}
+
+// handleCheck обрабатывает одиночную проверку
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	action := r.URL.Query().Get("action")
+	entitlements := r.Header.Get(locatorars.EntitlementsHeader)
+	s.record(Request{Action: action, Entitlements: entitlements, Header: r.Header.Clone()})
+
+	rule := s.match(action, entitlements)
+	if !s.apply(w, r, rule) {
+		return
+	}
+	writeJSON(w, response(action, rule))
+}
+
+// handleBatch обрабатывает пакетную проверку. Задержка равна наибольшей
+// из задержек подошедших правил, ошибка любого правила возвращается для всего пакета
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payload struct {
+		Actions []string `json:"actions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	entitlements := r.Header.Get(locatorars.EntitlementsHeader)
+	var (
+		results []locatorars.AccessResponse
+		failure *Rule
+		latency time.Duration
+	)
+	for _, action := range payload.Actions {
+		s.record(Request{Action: action, Entitlements: entitlements, Batch: true, Header: r.Header.Clone()})
+
+		rule := s.match(action, entitlements)
+		if rule.Latency > latency {
+			latency = rule.Latency
+		}
+		if failure == nil && (rule.Status != 0 || rule.Body != "" || rule.CloseConnection) {
+			failure = &rule
+		}
+		results = append(results, response(action, rule))
+	}
+
+	if failure == nil {
+		failure = &Rule{}
+	}
+	failure.Latency = latency
+	if !s.apply(w, r, *failure) {
+		return
+	}
+	writeJSON(w, map[string]interface{}{"results": results})
+}
+
+// record записывает полученный запрос
+func (s *Server) record(request Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, request)
+}
+
+// match находит первое подходящее правило и учитывает его применение
+func (s *Server) match(action, entitlements string) Rule {
+	granted := make(map[string]struct{})
+	for _, entitlement := range strings.Split(entitlements, "|") {
+		granted[strings.TrimSpace(entitlement)] = struct{}{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, rule := range s.rules {
+		if rule.Action != "" && rule.Action != action {
+			continue
+		}
+		if !hasAll(granted, rule.Entitlements) {
+			continue
+		}
+
+		matched := *rule
+		if rule.Times > 0 {
+			rule.Times--
+			if rule.Times == 0 {
+				s.rules = append(s.rules[:i:i], s.rules[i+1:]...)
+			}
+		}
+		return matched
+	}
+	return Rule{Action: action, Allowed: false}
+}
+
+// apply выполняет задержку и внедряет ошибку правила.
+// Возвращает false, если ответ уже записан или соединение закрыто
+func (s *Server) apply(w http.ResponseWriter, r *http.Request, rule Rule) bool {
+	if rule.Latency > 0 {
+		timer := time.NewTimer(rule.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return false
+		}
+	}
+
+	if rule.CloseConnection {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return false
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+
+	if rule.Status != 0 && rule.Status != http.StatusOK {
+		w.WriteHeader(rule.Status)
+		w.Write([]byte(rule.Body))
+		return false
+	}
+	if rule.Body != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(rule.Body))
+		return false
+	}
+	return true
+}
+
+// response формирует ответ сервиса по правилу
+func response(action string, rule Rule) locatorars.AccessResponse {
+	return locatorars.AccessResponse{
+		Action:  action,
+		Allowed: rule.Allowed,
+		Entity:  rule.Entity,
+		Message: rule.Message,
+		User:    rule.User,
+	}
+}
+
+// hasAll возвращает true, если в granted есть все required
+func hasAll(granted map[string]struct{}, required []string) bool {
+	for _, entitlement := range required {
+		if _, ok := granted[entitlement]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// writeJSON записывает JSON-ответ со статусом 200
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,47 @@
+package locatorars
+
+import "time"
+
+// Эндпоинты сервиса locator-ars для Metrics.ObserveRequest
+const (
+	EndpointCheck = "check"
+	EndpointBatch = "batch"
+
+	// EndpointChecker проверки checker, обернутого декоратором WithMetrics
+	EndpointChecker = "checker"
+)
+
+// Metrics принимает метрики проверок доступа. Реализация для Prometheus
+// находится в подпакете prometheus, библиотека от нее не зависит.
+// Методы вызываются конкурентно и не должны блокироваться
+type Metrics interface {
+	// ObserveDecision учитывает итог проверки доступа к action. Для RequireAny,
+	// RequireAll и RequireExpr action содержит выражение, например "a || b"
+	ObserveDecision(action string, outcome Outcome)
+
+	// ObserveRequest учитывает обращение к эндпоинту сервиса locator-ars.
+	// status равен 0, если ответ не получен (ошибка транспорта)
+	ObserveRequest(endpoint string, status int, duration time.Duration)
+
+	// ObserveCacheLookup учитывает обращение к кэшу решений
+	ObserveCacheLookup(hit bool)
+
+	// SetCircuitState сообщает текущее состояние автоматического выключателя
+	SetCircuitState(state CircuitState)
+}
+
+// noopMetrics реализация Metrics по умолчанию, ничего не делает
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveDecision(string, Outcome)           {}
+func (noopMetrics) ObserveRequest(string, int, time.Duration) {}
+func (noopMetrics) ObserveCacheLookup(bool)                   {}
+func (noopMetrics) SetCircuitState(CircuitState)              {}
+
+// metricsOrNoop возвращает metrics или пустую реализацию, если metrics равен nil
+func metricsOrNoop(metrics Metrics) Metrics {
+	if metrics == nil {
+		return noopMetrics{}
+	}
+	return metrics
+}
@@ -1,19 +1,56 @@
 package locatorars
 
 import (
-	"net/http"
-	"github.com/gin-gonic/gin"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DecisionContextKey ключ контекста фреймворка (gin, Echo, Fiber), под которым адаптеры
+// сохраняют полный ответ сервиса (*AccessResponse) при разрешении доступа
+const DecisionContextKey = "locatorars.decision"
+
 // Middleware предоставляет функциональность проверки прав доступа
 type Middleware struct {
-	client *AccessClient
-	config Config
-	logger Logger
+	checker   AccessChecker
+	config    Config
+	logger    Logger
+	metrics   Metrics
+	tracer    trace.Tracer
+	auditSink AuditSink
+	extractor EntitlementExtractor
+
+	// stale последние решения маршрутов с опцией ServeStale
+	stale *lastKnownGood
+
+	// reload текущая конфигурация, общая для всех снимков middleware (см. UpdateConfig)
+	reload *reloadState
 }
 
 // NewMiddleware создает новый экземпляр middleware для проверки прав доступа
 func NewMiddleware(config Config) *Middleware {
+	return newMiddleware(NewAccessClient(config), config, true)
+}
+
+// NewMiddlewareWithChecker создает middleware, принимающий решения через checker:
+// фейковую реализацию, локальную политику или AccessClient, обернутый декораторами.
+// Из config используются AllowOnFailure, параметры логирования и EntitlementExtractor
+func NewMiddlewareWithChecker(checker AccessChecker, config Config) *Middleware {
+	return newMiddleware(checker, config, false)
+}
+
+// newMiddleware создает middleware и делает его текущей конфигурацией.
+// ownsChecker означает, что checker создан из config и пересоздается при UpdateConfig
+func newMiddleware(checker AccessChecker, config Config, ownsChecker bool) *Middleware {
+	m := newSnapshot(checker, config)
+	m.reload = &reloadState{ownsChecker: ownsChecker}
+	m.reload.current.Store(m)
+	return m
+}
+
+// newSnapshot создает неизменяемый снимок middleware для конфигурации config
+func newSnapshot(checker AccessChecker, config Config) *Middleware {
 	var logger Logger
 	if config.Logger != nil {
 		logger = config.Logger
@@ -21,63 +58,20 @@ func NewMiddleware(config Config) *Middleware {
 		logger = NewDefaultLogger(config.LogLevel)
 	}
 
-	return &Middleware{
-		client: NewAccessClient(config),
-		config: config,
-		logger: logger,
+	extractor := config.EntitlementExtractor
+	if extractor == nil {
+		extractor = HeaderExtractor(EntitlementsHeader)
 	}
-}
 
-// RequireAction создает middleware, который требует указанное действие
-func (m *Middleware) RequireAction(action string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		m.logger.Debug("Checking access for action: %s", action)
-
-		// Получаем необходимые заголовки
-		entitlements := c.GetHeader("X-Authentik-Entitlements")
-		if entitlements == "" {
-			m.logger.Info("Missing X-Authentik-Entitlements header in request")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Missing X-Authentik-Entitlements header",
-			})
-			return
-		}
-
-		// Application header is no longer required as authentik provides entitlements for specific applications
-		application := c.GetHeader("Application")
-		if application == "" {
-			// Use default application identifier or extract from context if needed
-			application = "default"
-		}
-
-		m.logger.Debug("Headers found: Application=%s, Entitlements present=%v", application, len(entitlements) > 0)
-
-		// Проверяем доступ
-		allowed, err := m.client.CheckAccess(action, entitlements)
-		if err != nil {
-			m.logger.Error("Error checking access: %v", err)
-			if !m.config.AllowOnFailure {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-					"error": "Failed to check access",
-				})
-				return
-			}
-			m.logger.Info("Access allowed on failure due to configuration")
-			// Если настроено разрешать при ошибке, продолжаем выполнение
-		}
-
-		// Если доступ запрещен, возвращаем ошибку
-		if !allowed {
-			m.logger.Info("Access denied for action: %s, application: %s", action, application)
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-				"error": "Access denied",
-			})
-			return
-		}
-
-		m.logger.Info("Access granted for action: %s, application: %s", action, application)
-		// Если доступ разрешен, продолжаем выполнение следующего обработчика
-		c.Next()
+	return &Middleware{
+		checker:   checker,
+		config:    config,
+		logger:    logger,
+		metrics:   metricsOrNoop(config.Metrics),
+		tracer:    newTracer(config),
+		auditSink: config.AuditSink,
+		extractor: extractor,
+		stale:     newLastKnownGood(config.LastKnownGood),
 	}
 }
 
@@ -85,42 +79,112 @@ func (m *Middleware) RequireAction(action string) gin.HandlerFunc {
 // Возвращает true если доступ разрешен, false если запрещен
 // Может использоваться напрямую в условных выражениях
 func (m *Middleware) CheckAccess(action, entitlements string) bool {
+	return m.CheckAccessContext(context.Background(), action, entitlements)
+}
+
+// CheckAccessContext проверяет права доступа аналогично CheckAccess,
+// но прерывает проверку при отмене переданного контекста.
+// При отмене контекста возвращает false независимо от AllowOnFailure
+func (m *Middleware) CheckAccessContext(ctx context.Context, action, entitlements string) bool {
+	m = m.snapshot()
 	m.logger.Debug("Direct check for action: %s", action)
 
-	allowed, err := m.client.CheckAccess(action, entitlements)
+	startTime := time.Now()
+	decision, err := m.checker.CheckAccessDetailed(ctx, action, entitlements)
 	if err != nil {
-		m.logger.Error("Error in direct access check: %v", err)
 		// Возвращаем значение в соответствии с политикой обработки ошибок
-		return m.config.AllowOnFailure
+		outcome := failureOutcome(ctx, m.config.AllowOnFailure)
+		if outcome == OutcomeTimeout || outcome == OutcomeCancelled {
+			m.logger.Info("Direct access check cancelled for action: %s: %v", action, ctx.Err())
+		} else {
+			m.logger.Error("Error in direct access check: %v", err)
+		}
+		m.recordDecision(ctx, action, startTime, AuthResult{Outcome: outcome, Err: err})
+		return outcome == OutcomeFailOpen
 	}
 
+	allowed := decision.Allowed
 	if allowed {
 		m.logger.Info("Direct access check: granted for action: %s", action)
+		m.recordDecision(ctx, action, startTime, AuthResult{Outcome: OutcomeAllowed, Decision: decision})
 	} else {
 		m.logger.Info("Direct access check: denied for action: %s", action)
+		m.recordDecision(ctx, action, startTime, AuthResult{Outcome: OutcomeDenied, Decision: decision})
 	}
 
 	return allowed
 }
 
-// CheckAccessFromContext проверяет права доступа, извлекая Entitlements и приложение из gin.Context
-// Удобно для использования в обработчиках
-func (m *Middleware) CheckAccessFromContext(c *gin.Context, action string) bool {
-	entitlements := c.GetHeader("X-Authentik-Entitlements")
+// CheckActions проверяет права доступа сразу для нескольких действий и возвращает карту решений.
+// Для действий, проверить которые не удалось, решение определяется политикой AllowOnFailure.
+// Решение по каждому действию учитывается в метриках и журнале аудита
+func (m *Middleware) CheckActions(ctx context.Context, entitlements string, actions []string) (results map[string]bool, err error) {
+	m = m.snapshot()
+	m.logger.Debug("Batch check for %d actions", len(actions))
+
+	ctx, span := m.tracer.Start(ctx, "locatorars.AuthorizeActions", trace.WithAttributes(AttrAction.StringSlice(actions)))
+	defer func() { endCheckSpan(span, nil, err) }()
+	startTime := time.Now()
+
+	if batch, ok := m.checker.(batchChecker); ok {
+		results, err = batch.CheckActions(ctx, entitlements, actions)
+	} else {
+		results, err = m.checkActions(ctx, entitlements, actions)
+	}
+	if err != nil {
+		m.logger.Error("Error in batch access check: %v", err)
+	}
+
+	m.recordActions(ctx, startTime, results, err)
+	return results, err
+}
+
+// recordActions записывает решения CheckActions по каждому действию в метрики и журнал аудита.
+// Ошибка, которую нельзя отнести к отдельным действиям, например ошибка конфигурации, относится ко всем
+func (m *Middleware) recordActions(ctx context.Context, startTime time.Time, results map[string]bool, err error) {
+	failed := actionErrors(err)
+	for action, allowed := range results {
+		result := AuthResult{Outcome: OutcomeDenied}
+		if allowed {
+			result.Outcome = OutcomeAllowed
+		}
 
-	m.logger.Debug("Context check for action: %s", action)
+		actionErr, ok := failed[action]
+		if !ok && err != nil && len(failed) == 0 {
+			actionErr, ok = err, true
+		}
+		if ok {
+			result = AuthResult{Outcome: failureOutcome(ctx, m.config.AllowOnFailure), Err: actionErr}
+		}
+		m.recordDecision(ctx, action, startTime, result)
+	}
+}
 
-	if entitlements == "" {
-		m.logger.Info("Context access check: missing headers for action: %s", action)
-		return false
+// checkActions проверяет действия параллельно, если checker не поддерживает пакетную проверку
+func (m *Middleware) checkActions(ctx context.Context, entitlements string, actions []string) (map[string]bool, error) {
+	results := make(map[string]bool, len(actions))
+	pending := make([]string, 0, len(actions))
+	for _, action := range actions {
+		if _, ok := results[action]; !ok {
+			results[action] = false
+			pending = append(pending, action)
+		}
 	}
 
-	return m.CheckAccess(action, entitlements)
+	return results, checkParallel(ctx, m.config.BatchConcurrency, pending, results, func(ctx context.Context, action string) (bool, error) {
+		allowed, err := checkAllowed(ctx, m.checker, action, entitlements)
+		if err != nil {
+			// Отмена запроса вызывающей стороной не является отказом сервиса
+			return ctx.Err() == nil && m.config.AllowOnFailure, err
+		}
+		return allowed, nil
+	})
 }
 
-// SetLogLevel устанавливает уровень логирования для middleware
+// SetLogLevel устанавливает уровень логирования для middleware.
+// Действует до следующей перезагрузки конфигурации, которая задает уровень из Config.LogLevel
 func (m *Middleware) SetLogLevel(level LogLevel) {
-	if defaultLogger, ok := m.logger.(*DefaultLogger); ok {
-		defaultLogger.level = level
+	if defaultLogger, ok := m.snapshot().logger.(*DefaultLogger); ok {
+		defaultLogger.level.Store(int32(level))
 	}
 }
@@ -0,0 +1,147 @@
+// Package prometheus реализует locatorars.Metrics для Prometheus.
+//
+// Использование:
+//
+//	metrics := prometheus.NewMetrics(prometheus.Options{})
+//	prom.MustRegister(metrics)
+//
+// Повторная регистрация метрик с тем же префиксом возвращает prometheus.AlreadyRegisteredError,
+// в поле ExistingCollector которой находятся ранее зарегистрированные метрики.
+//
+//	config := locatorars.DefaultConfig()
+//	config.Metrics = metrics
+//
+// Экспортируемые метрики (префикс задается Options.Namespace):
+//
+//	locatorars_decisions_total{action, outcome}            итоги проверок доступа
+//	locatorars_ars_request_duration_seconds{endpoint, code} длительность обращений к locator-ars
+//	locatorars_cache_lookups_total{result}                 обращения к кэшу решений (hit, miss)
+//	locatorars_circuit_breaker_state                       состояние выключателя: 0 - closed, 1 - open, 2 - half-open
+//
+// Доля попаданий в кэш вычисляется запросом:
+//
+//	sum(rate(locatorars_cache_lookups_total{result="hit"}[5m])) / sum(rate(locatorars_cache_lookups_total[5m]))
+package prometheus
+
+import (
+	"strconv"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+)
+
+const defaultNamespace = "locatorars"
+
+// Options параметры метрик
+type Options struct {
+	// Префикс имен метрик
+	// По умолчанию: "locatorars"
+	Namespace string
+
+	// Постоянные метки всех метрик, например имя сервиса
+	ConstLabels prom.Labels
+
+	// Границы гистограммы длительности обращений к сервису в секундах
+	// По умолчанию: от 5 мс до 5 секунд
+	Buckets []float64
+}
+
+// Metrics реализует locatorars.Metrics и prometheus.Collector
+type Metrics struct {
+	decisions       *prom.CounterVec
+	requestDuration *prom.HistogramVec
+	cacheLookups    *prom.CounterVec
+	circuitState    prom.Gauge
+}
+
+var (
+	_ locatorars.Metrics = (*Metrics)(nil)
+	_ prom.Collector     = (*Metrics)(nil)
+)
+
+// NewMetrics создает метрики. Их нужно зарегистрировать в prometheus.Registerer
+func NewMetrics(opts Options) *Metrics {
+	if opts.Namespace == "" {
+		opts.Namespace = defaultNamespace
+	}
+	if len(opts.Buckets) == 0 {
+		opts.Buckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+	}
+
+	return &Metrics{
+		decisions: prom.NewCounterVec(prom.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "decisions_total",
+			Help:        "Access check outcomes by action.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"action", "outcome"}),
+		requestDuration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Name:        "ars_request_duration_seconds",
+			Help:        "Duration of locator-ars requests by endpoint and HTTP status code (0 for transport errors).",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.Buckets,
+		}, []string{"endpoint", "code"}),
+		cacheLookups: prom.NewCounterVec(prom.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "cache_lookups_total",
+			Help:        "Access decision cache lookups by result (hit, miss).",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"result"}),
+		circuitState: prom.NewGauge(prom.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "circuit_breaker_state",
+			Help:        "Circuit breaker state: 0 - closed, 1 - open, 2 - half-open.",
+			ConstLabels: opts.ConstLabels,
+		}),
+	}
+}
+
+// ObserveDecision учитывает итог проверки доступа
+func (m *Metrics) ObserveDecision(action string, outcome locatorars.Outcome) {
+	m.decisions.WithLabelValues(action, outcome.String()).Inc()
+}
+
+// ObserveRequest учитывает обращение к сервису locator-ars
+func (m *Metrics) ObserveRequest(endpoint string, status int, duration time.Duration) {
+	m.requestDuration.WithLabelValues(endpoint, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ObserveCacheLookup учитывает обращение к кэшу решений
+func (m *Metrics) ObserveCacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(result).Inc()
+}
+
+// SetCircuitState сохраняет состояние автоматического выключателя
+func (m *Metrics) SetCircuitState(state locatorars.CircuitState) {
+	var value float64
+	switch state {
+	case locatorars.CircuitOpen:
+		value = 1
+	case locatorars.CircuitHalfOpen:
+		value = 2
+	}
+	m.circuitState.Set(value)
+}
+
+// Describe реализует prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prom.Desc) {
+	m.decisions.Describe(ch)
+	m.requestDuration.Describe(ch)
+	m.cacheLookups.Describe(ch)
+	m.circuitState.Describe(ch)
+}
+
+// Collect реализует prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prom.Metric) {
+	m.decisions.Collect(ch)
+	m.requestDuration.Collect(ch)
+	m.cacheLookups.Collect(ch)
+	m.circuitState.Collect(ch)
+}
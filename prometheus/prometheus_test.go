@@ -0,0 +1,184 @@
+package prometheus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+	"github.com/LT-Devs/locator-ars-go-lib/prometheus"
+)
+
+// gather собирает метрики реестра по имени
+func gather(t *testing.T, registry *prom.Registry) map[string]*dto.MetricFamily {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+	return byName
+}
+
+// find возвращает метрику семейства с указанными значениями меток
+func find(t *testing.T, family *dto.MetricFamily, labels map[string]string) *dto.Metric {
+	t.Helper()
+	if family == nil {
+		t.Fatal("metric family is not exported")
+	}
+	for _, metric := range family.GetMetric() {
+		matched := 0
+		for _, pair := range metric.GetLabel() {
+			if value, ok := labels[pair.GetName()]; ok && value == pair.GetValue() {
+				matched++
+			}
+		}
+		if matched == len(labels) {
+			return metric
+		}
+	}
+	t.Fatalf("%s has no metric with labels %v", family.GetName(), labels)
+	return nil
+}
+
+func TestMetrics(t *testing.T) {
+	ars := locatorarstest.NewServer(t)
+	ars.Allow("reports.view", "reports")
+	ars.Deny("reports.edit")
+	ars.Fail("billing.view", http.StatusServiceUnavailable)
+
+	metrics := prometheus.NewMetrics(prometheus.Options{ConstLabels: prom.Labels{"service": "reports"}})
+	registry := prom.NewRegistry()
+	registry.MustRegister(metrics)
+
+	config := ars.Config()
+	config.Metrics = metrics
+	config.Cache.Enabled = true
+	config.CircuitBreaker.Enabled = true
+	config.CircuitBreaker.FailureThreshold = 1
+	m := locatorars.NewMiddleware(config)
+
+	ctx := context.Background()
+	m.Authorize(ctx, "reports.view", "reports")
+	m.Authorize(ctx, "reports.view", "reports") // из кэша
+	m.Authorize(ctx, "reports.edit", "reports")
+	m.Authorize(ctx, "billing.view", "reports") // размыкает выключатель
+
+	families := gather(t, registry)
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	wantNames := []string{
+		"locatorars_ars_request_duration_seconds",
+		"locatorars_cache_lookups_total",
+		"locatorars_circuit_breaker_state",
+		"locatorars_decisions_total",
+	}
+	if !slices.Equal(names, wantNames) {
+		t.Fatalf("metrics %v, want %v", names, wantNames)
+	}
+
+	decisions := families["locatorars_decisions_total"]
+	for _, tt := range []struct {
+		action, outcome string
+		want            float64
+	}{
+		{action: "reports.view", outcome: "allowed", want: 2},
+		{action: "reports.edit", outcome: "denied", want: 1},
+		{action: "billing.view", outcome: "error", want: 1},
+	} {
+		metric := find(t, decisions, map[string]string{"action": tt.action, "outcome": tt.outcome, "service": "reports"})
+		if got := metric.GetCounter().GetValue(); got != tt.want {
+			t.Errorf("decisions_total{action=%q, outcome=%q} = %v, want %v", tt.action, tt.outcome, got, tt.want)
+		}
+	}
+
+	cache := families["locatorars_cache_lookups_total"]
+	if got := find(t, cache, map[string]string{"result": "hit"}).GetCounter().GetValue(); got != 1 {
+		t.Errorf("cache hits %v, want 1", got)
+	}
+	if got := find(t, cache, map[string]string{"result": "miss"}).GetCounter().GetValue(); got != 3 {
+		t.Errorf("cache misses %v, want 3", got)
+	}
+
+	durations := families["locatorars_ars_request_duration_seconds"]
+	if durations.GetType() != dto.MetricType_HISTOGRAM {
+		t.Fatalf("request duration type %v, want histogram", durations.GetType())
+	}
+	ok := find(t, durations, map[string]string{"endpoint": locatorars.EndpointCheck, "code": "200"}).GetHistogram()
+	if ok.GetSampleCount() != 2 {
+		t.Errorf("successful requests %d, want 2", ok.GetSampleCount())
+	}
+	var bounds []float64
+	for _, bucket := range ok.GetBucket() {
+		bounds = append(bounds, bucket.GetUpperBound())
+	}
+	if want := []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}; !slices.Equal(bounds, want) {
+		t.Errorf("buckets %v, want %v", bounds, want)
+	}
+	if got := find(t, durations, map[string]string{"endpoint": locatorars.EndpointCheck, "code": "503"}).GetHistogram().GetSampleCount(); got != 1 {
+		t.Errorf("failed requests %d, want 1", got)
+	}
+
+	state := families["locatorars_circuit_breaker_state"]
+	if got := find(t, state, map[string]string{"service": "reports"}).GetGauge().GetValue(); got != 1 {
+		t.Errorf("circuit breaker state %v, want 1 (open)", got)
+	}
+}
+
+func TestMetricsOptions(t *testing.T) {
+	metrics := prometheus.NewMetrics(prometheus.Options{Namespace: "auth", Buckets: []float64{.1, 1}})
+	registry := prom.NewRegistry()
+	registry.MustRegister(metrics)
+
+	metrics.ObserveRequest(locatorars.EndpointBatch, 0, 0)
+	metrics.SetCircuitState(locatorars.CircuitHalfOpen)
+
+	families := gather(t, registry)
+	durations := find(t, families["auth_ars_request_duration_seconds"], map[string]string{"endpoint": "batch", "code": "0"}).GetHistogram()
+	var bounds []float64
+	for _, bucket := range durations.GetBucket() {
+		bounds = append(bounds, bucket.GetUpperBound())
+	}
+	if !slices.Equal(bounds, []float64{.1, 1}) {
+		t.Errorf("buckets %v, want [0.1 1]", bounds)
+	}
+	if got := families["auth_circuit_breaker_state"].GetMetric()[0].GetGauge().GetValue(); got != 2 {
+		t.Errorf("circuit breaker state %v, want 2 (half-open)", got)
+	}
+}
+
+func TestMetricsDoubleRegistration(t *testing.T) {
+	registry := prom.NewRegistry()
+	metrics := prometheus.NewMetrics(prometheus.Options{})
+	registry.MustRegister(metrics)
+
+	// Повторная регистрация, например при пересоздании middleware, возвращает уже
+	// зарегистрированный сборщик, который можно использовать дальше
+	for _, collector := range []prom.Collector{metrics, prometheus.NewMetrics(prometheus.Options{})} {
+		err := registry.Register(collector)
+		var already prom.AlreadyRegisteredError
+		if !errors.As(err, &already) {
+			t.Fatalf("err %v, want AlreadyRegisteredError", err)
+		}
+		if already.ExistingCollector != metrics {
+			t.Error("ExistingCollector is not the registered metrics")
+		}
+	}
+
+	// Метрики с другим префиксом регистрируются в том же реестре
+	if err := registry.Register(prometheus.NewMetrics(prometheus.Options{Namespace: "other"})); err != nil {
+		t.Fatalf("Register with another namespace: %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package locatorars
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// defaultWatchInterval интервал опроса файла конфигурации в WatchConfigFile по умолчанию
+const defaultWatchInterval = 5 * time.Second
+
+// reloadState текущая конфигурация middleware. Каждая конфигурация хранится
+// в неизменяемом снимке *Middleware, который запрос получает один раз в начале
+// обработки, поэтому начатые проверки завершаются на старой конфигурации
+type reloadState struct {
+	// mu упорядочивает конкурентные вызовы UpdateConfig
+	mu      sync.Mutex
+	current atomic.Pointer[Middleware]
+
+	// ownsChecker устанавливается, если middleware создан NewMiddleware
+	// и AccessClient нужно пересоздавать из новой конфигурации
+	ownsChecker bool
+}
+
+// snapshot возвращает снимок текущей конфигурации
+func (m *Middleware) snapshot() *Middleware {
+	return m.reload.current.Load()
+}
+
+// Config возвращает текущую конфигурацию middleware
+func (m *Middleware) Config() Config {
+	return m.snapshot().config
+}
+
+// UpdateConfig атомарно заменяет конфигурацию middleware. Проверки, начатые до вызова,
+// завершаются на старой конфигурации, новые используют новую.
+//
+// Для middleware, созданного NewMiddleware, AccessClient пересоздается, только если изменились
+// параметры обращения к сервису: URL, таймауты, транспорт, кэш, повторы, выключатель, режим
+// или локальная политика. Тогда кэш решений, последние подтвержденные решения и состояние
+// выключателя начинаются заново. Изменение только AllowOnFailure, уровня логирования, журнала
+// аудита или источника Entitlements их сохраняет. Для NewMiddlewareWithChecker checker
+// сохраняется, а применяются только AllowOnFailure, логирование и источник Entitlements.
+//
+// Logger, Metrics, TracerProvider, Propagator, AuditSink, EntitlementExtractor, HTTPClient,
+// Transport и LocalEvaluator, не заданные в config, берутся из текущей конфигурации,
+// поэтому config можно получать из ConfigFromFile или ConfigFromEnv.
+// Конфигурация проверяется через Config.Validate в обоих случаях, но для NewMiddlewareWithChecker
+// без проверки URL и BatchURL. При ошибке продолжает действовать текущая
+func (m *Middleware) UpdateConfig(config Config) error {
+	state := m.reload
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	current := state.current.Load()
+	// Унаследованный HTTP-клиент игнорирует Timeout, Transport и TLS новой конфигурации
+	ignoredTransport := config.HTTPClient == nil && current.config.HTTPClient != nil &&
+		(config.Timeout != current.config.Timeout || config.TLS != current.config.TLS || config.Transport != nil)
+	config = inheritRuntimeConfig(config, current.config)
+
+	// Адреса сервиса проверяются, только если по ним создается AccessClient
+	if err := config.validate(state.ownsChecker); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	checker := current.checker
+	if state.ownsChecker {
+		if client, ok := current.checker.(*AccessClient); ok && sameClientConfig(config, current.config) {
+			checker = client.reconfigured(config)
+		} else {
+			checker = NewAccessClient(config)
+		}
+	}
+
+	next := newSnapshot(checker, config)
+	next.reload = state
+	if config.LastKnownGood.GraceWindow == current.config.LastKnownGood.GraceWindow &&
+		config.LastKnownGood.MaxEntries == current.config.LastKnownGood.MaxEntries {
+		// Последние известные решения нужны как раз тогда, когда меняют адрес недоступного сервиса
+		next.stale = current.stale
+	}
+	state.current.Store(next)
+
+	if ignoredTransport {
+		next.logger.Error("Configuration reload keeps the current HTTPClient, new timeout, transport and TLS settings are ignored")
+	}
+	next.logger.Info("Configuration reloaded: url=%s, allow_on_failure=%v, timeout=%v, cache=%v",
+		config.URL, config.AllowOnFailure, config.Timeout, config.Cache.Enabled)
+	return nil
+}
+
+// inheritRuntimeConfig дополняет config объектами времени выполнения из current,
+// которые нельзя задать в файле или переменных окружения
+func inheritRuntimeConfig(config, current Config) Config {
+	if config.Logger == nil {
+		config.Logger = current.Logger
+	}
+	if config.Metrics == nil {
+		config.Metrics = current.Metrics
+	}
+	if config.TracerProvider == nil {
+		config.TracerProvider = current.TracerProvider
+	}
+	if config.Propagator == nil {
+		config.Propagator = current.Propagator
+	}
+	if config.AuditSink == nil {
+		config.AuditSink = current.AuditSink
+	}
+	if config.EntitlementExtractor == nil {
+		config.EntitlementExtractor = current.EntitlementExtractor
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = current.HTTPClient
+	}
+	if config.Transport == nil && config.TLS.isZero() {
+		config.Transport = current.Transport
+	}
+	// Файл политики в новой конфигурации важнее ранее переданного вычислителя
+	if config.LocalEvaluator == nil && config.LocalPolicyFile == "" {
+		config.LocalEvaluator = current.LocalEvaluator
+	}
+	return config
+}
+
+// sameClientConfig возвращает true, если AccessClient, созданный из current, подходит для config:
+// отличаются только AllowOnFailure, уровень логирования и параметры, которые AccessClient не использует.
+// Файл локальной политики перечитывается при каждой перезагрузке
+func sameClientConfig(config, current Config) bool {
+	return config.URL == current.URL &&
+		config.BatchURL == current.BatchURL &&
+		config.BatchConcurrency == current.BatchConcurrency &&
+		config.Timeout == current.Timeout &&
+		config.TLS == current.TLS &&
+		config.Cache == current.Cache &&
+		config.Retry == current.Retry &&
+		config.CircuitBreaker == current.CircuitBreaker &&
+		config.LastKnownGood == current.LastKnownGood &&
+		config.DisableCoalescing == current.DisableCoalescing &&
+		config.Mode == current.Mode &&
+		config.LocalPolicyFile == "" && current.LocalPolicyFile == "" &&
+		config.LocalEvaluator == current.LocalEvaluator &&
+		config.HTTPClient == current.HTTPClient &&
+		sameObject(config.Transport, current.Transport) &&
+		sameObject(config.Logger, current.Logger) &&
+		sameObject(config.Metrics, current.Metrics) &&
+		sameObject(config.TracerProvider, current.TracerProvider) &&
+		sameObject(config.Propagator, current.Propagator)
+}
+
+// sameObject сравнивает объекты времени выполнения из конфигурации.
+// Значения несравнимых типов, например функции, считаются разными
+func sameObject(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+// reconfigured возвращает клиент с AllowOnFailure и уровнем логирования из config, который
+// использует HTTP-клиент, кэш, последние подтвержденные решения, выключатель и локальную политику ac.
+// Проверки, начатые через ac, завершаются с прежней политикой AllowOnFailure
+func (ac *AccessClient) reconfigured(config Config) *AccessClient {
+	if logger, ok := ac.logger.(*DefaultLogger); ok && config.Logger == nil {
+		// Логгер общий с выключателем и объединением проверок, поэтому уровень меняется на месте
+		logger.level.Store(int32(config.LogLevel))
+	}
+
+	next := &AccessClient{
+		config:     config,
+		client:     ac.client,
+		logger:     ac.logger,
+		metrics:    ac.metrics,
+		cache:      ac.cache,
+		lkg:        ac.lkg,
+		flights:    ac.flights,
+		tracer:     ac.tracer,
+		propagator: ac.propagator,
+		retry:      ac.retry,
+		breaker:    ac.breaker,
+		local:      ac.local,
+		localErr:   ac.localErr,
+		initErr:    ac.initErr,
+	}
+	next.batchUnsupported.Store(ac.batchUnsupported.Load())
+	return next
+}
+
+// ReloadOnSignal перезагружает конфигурацию через load при получении сигнала
+// (по умолчанию SIGHUP), пока не отменен ctx. Ошибки load и UpdateConfig
+// логируются, при этом продолжает действовать текущая конфигурация:
+//
+//	arsMiddleware.ReloadOnSignal(ctx, func() (locatorars.Config, error) {
+//		return locatorars.ConfigFromFile("/etc/app/locator-ars.yaml")
+//	})
+func (m *Middleware) ReloadOnSignal(ctx context.Context, load func() (Config, error), signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case sig := <-ch:
+				m.snapshot().logger.Info("Received %v, reloading configuration", sig)
+				m.reloadFrom(load)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// WatchConfigFile проверяет файл конфигурации каждые interval (по умолчанию 5 секунд)
+// и при изменении времени модификации или размера загружает его через ConfigFromFile,
+// пока не отменен ctx. Ошибки логируются, при этом продолжает действовать текущая конфигурация
+func (m *Middleware) WatchConfigFile(ctx context.Context, path string, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	last, _ := os.Stat(path)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				info, err := os.Stat(path)
+				if err != nil {
+					m.snapshot().logger.Error("Failed to stat config file %s: %v", path, err)
+					continue
+				}
+				if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+					continue
+				}
+				last = info
+
+				m.snapshot().logger.Info("Config file %s changed, reloading configuration", path)
+				m.reloadFrom(func() (Config, error) { return ConfigFromFile(path) })
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// reloadFrom загружает конфигурацию и применяет ее, логируя ошибки
+func (m *Middleware) reloadFrom(load func() (Config, error)) {
+	config, err := load()
+	if err == nil {
+		err = m.UpdateConfig(config)
+	}
+	if err != nil {
+		m.snapshot().logger.Error("Failed to reload configuration, keeping current one: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package locatorars_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+func TestUpdateConfigConcurrentWithChecks(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	s.Allow("reports.view", "reports")
+	s.Deny("reports.edit")
+
+	m := s.Middleware()
+	handler := m.RequireActionHTTP("reports.view")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ctx.Err() == nil; i++ {
+			config := s.Config()
+			config.AllowOnFailure = i%2 == 0
+			config.Cache.Enabled = i%3 == 0
+			config.Timeout = time.Duration(1+i%5) * time.Second
+			if err := m.UpdateConfig(config); err != nil {
+				t.Errorf("UpdateConfig: %v", err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+				req.Header.Set(locatorars.EntitlementsHeader, "reports")
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code != http.StatusOK {
+					t.Errorf("RequireActionHTTP: status %d, want %d", rec.Code, http.StatusOK)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				results, err := m.CheckActions(context.Background(), "reports", []string{"reports.view", "reports.edit"})
+				if err != nil || !results["reports.view"] || results["reports.edit"] {
+					t.Errorf("CheckActions: %v, %v", results, err)
+					return
+				}
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	wg.Wait()
+}
+
+func TestUpdateConfigValidatesWithChecker(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	m := locatorars.NewMiddlewareWithChecker(locatorars.NewAccessClient(s.Config()), s.Config())
+
+	invalid := []func(c *locatorars.Config){
+		func(c *locatorars.Config) { c.LogLevel = locatorars.LogLevel(42) },
+		func(c *locatorars.Config) { c.LastKnownGood.GraceWindow = -time.Second },
+		func(c *locatorars.Config) { c.Timeout = -time.Second },
+	}
+	for i, mutate := range invalid {
+		config := s.Config()
+		mutate(&config)
+		if err := m.UpdateConfig(config); err == nil {
+			t.Errorf("config %d: UpdateConfig accepted an invalid config", i)
+		}
+	}
+
+	config := s.Config()
+	config.AllowOnFailure = true
+	if err := m.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if !m.Config().AllowOnFailure {
+		t.Error("valid config was not applied")
+	}
+}
+
+func TestUpdateConfigWithCheckerWithoutURL(t *testing.T) {
+	checker := locatorars.CheckerFunc(func(ctx context.Context, action, entitlements string) (*locatorars.AccessResponse, error) {
+		return nil, locatorars.ErrUnavailable
+	})
+	m := locatorars.NewMiddlewareWithChecker(checker, locatorars.Config{})
+
+	// Собственный checker не обращается к сервису, поэтому URL в конфигурации не нужен
+	if err := m.UpdateConfig(locatorars.Config{AllowOnFailure: true}); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if result := m.Authorize(context.Background(), "reports.view", "reports"); result.Outcome != locatorars.OutcomeFailOpen {
+		t.Errorf("outcome %v after reload, want fail-open", result.Outcome)
+	}
+
+	if err := m.UpdateConfig(locatorars.Config{Timeout: -time.Second}); err == nil {
+		t.Error("UpdateConfig accepted a negative timeout")
+	}
+
+	// Для middleware, создающего AccessClient, URL по-прежнему обязателен
+	if err := locatorars.NewMiddleware(locatorars.DefaultConfig()).UpdateConfig(locatorars.Config{AllowOnFailure: true}); err == nil {
+		t.Error("UpdateConfig accepted a config without URL for an owned AccessClient")
+	}
+}
+
+func TestUpdateConfigKeepsClientState(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	s.Allow("reports.view", "reports")
+	s.Fail("billing.view", http.StatusServiceUnavailable)
+
+	newConfig := func() locatorars.Config {
+		config := s.Config()
+		config.Cache.Enabled = true
+		config.CircuitBreaker.Enabled = true
+		config.CircuitBreaker.FailureThreshold = 1
+		config.CircuitBreaker.CoolDown = time.Hour
+		return config
+	}
+	m := locatorars.NewMiddleware(newConfig())
+	ctx := context.Background()
+
+	m.Authorize(ctx, "reports.view", "reports") // кэшируется
+	m.Authorize(ctx, "billing.view", "reports") // размыкает выключатель
+
+	// Изменение политики отказа и уровня логирования не сбрасывает кэш и выключатель
+	config := newConfig()
+	config.AllowOnFailure = true
+	config.LogLevel = locatorars.LogLevelDebug
+	if err := m.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if result := m.Authorize(ctx, "reports.view", "reports"); result.Outcome != locatorars.OutcomeAllowed {
+		t.Fatalf("outcome %v, want allowed from cache", result.Outcome)
+	}
+	result := m.Authorize(ctx, "billing.view", "reports")
+	if result.Outcome != locatorars.OutcomeFailOpen || !errors.Is(result.Err, locatorars.ErrCircuitOpen) {
+		t.Fatalf("outcome %v, err %v, want fail-open with open circuit", result.Outcome, result.Err)
+	}
+	if s.Calls("reports.view") != 1 || s.Calls("billing.view") != 1 {
+		t.Fatalf("service called %d and %d times, want once per action", s.Calls("reports.view"), s.Calls("billing.view"))
+	}
+
+	// Изменение параметров обращения к сервису создает новый AccessClient
+	config.Timeout = 2 * time.Second
+	if err := m.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if result := m.Authorize(ctx, "reports.view", "reports"); result.Outcome != locatorars.OutcomeAllowed {
+		t.Fatalf("outcome %v, want allowed", result.Outcome)
+	}
+	if s.Calls("reports.view") != 2 {
+		t.Errorf("service called %d times, want the new client to start with an empty cache", s.Calls("reports.view"))
+	}
+}
+
+// messageLogger запоминает сообщения об ошибках
+type messageLogger struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (l *messageLogger) Debug(format string, args ...interface{}) {}
+func (l *messageLogger) Info(format string, args ...interface{})  {}
+
+func (l *messageLogger) Error(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func (l *messageLogger) warned() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, message := range l.errors {
+		if strings.Contains(message, "keeps the current HTTPClient") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestUpdateConfigWarnsAboutInheritedHTTPClient(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+
+	tests := []struct {
+		name   string
+		modify func(c *locatorars.Config)
+		want   bool
+	}{
+		{name: "same transport settings", modify: func(c *locatorars.Config) { c.AllowOnFailure = true }},
+		{name: "new timeout", modify: func(c *locatorars.Config) { c.Timeout = time.Second }, want: true},
+		{name: "new TLS", modify: func(c *locatorars.Config) { c.TLS.ServerName = "ars.internal" }, want: true},
+		{name: "new transport", modify: func(c *locatorars.Config) { c.Transport = http.DefaultTransport }, want: true},
+		{name: "new HTTP client", modify: func(c *locatorars.Config) {
+			c.HTTPClient = &http.Client{}
+			c.Timeout = time.Second
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &messageLogger{}
+			config := s.Config()
+			config.Logger = logger
+			config.HTTPClient = &http.Client{Timeout: 3 * time.Second}
+			m := locatorars.NewMiddleware(config)
+
+			// Конфигурация из файла не содержит HTTPClient и наследует его
+			update := s.Config()
+			tt.modify(&update)
+			if err := m.UpdateConfig(update); err != nil {
+				t.Fatalf("UpdateConfig: %v", err)
+			}
+			if logger.warned() != tt.want {
+				t.Errorf("warning logged: %v, want %v", logger.warned(), tt.want)
+			}
+		})
+	}
+}
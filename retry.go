@@ -0,0 +1,171 @@
+package locatorars
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 2 * time.Second
+	defaultRetryMultiplier     = 2.0
+)
+
+// RetryConfig определяет политику повторных запросов к сервису locator-ars.
+// Повторяются только временные сбои: ошибки соединения, статусы 502, 503, 504,
+// а также 429 с заголовком Retry-After
+type RetryConfig struct {
+	// Максимальное количество попыток, включая первую
+	// По умолчанию: 1 (без повторов)
+	MaxAttempts int
+
+	// Задержка перед первым повтором
+	// По умолчанию: 100 миллисекунд
+	InitialBackoff time.Duration
+
+	// Максимальная задержка между попытками. Если Retry-After из ответа 429
+	// превышает это значение, запрос не повторяется
+	// По умолчанию: 2 секунды
+	MaxBackoff time.Duration
+
+	// Множитель экспоненциального роста задержки
+	// По умолчанию: 2
+	Multiplier float64
+}
+
+// retryPolicy нормализованная политика повторов
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	multiplier     float64
+}
+
+// newRetryPolicy создает политику повторов, подставляя значения по умолчанию
+func newRetryPolicy(config RetryConfig) retryPolicy {
+	policy := retryPolicy{
+		maxAttempts:    config.MaxAttempts,
+		initialBackoff: config.InitialBackoff,
+		maxBackoff:     config.MaxBackoff,
+		multiplier:     config.Multiplier,
+	}
+	if policy.maxAttempts < 1 {
+		policy.maxAttempts = 1
+	}
+	if policy.initialBackoff <= 0 {
+		policy.initialBackoff = defaultRetryInitialBackoff
+	}
+	if policy.maxBackoff <= 0 {
+		policy.maxBackoff = defaultRetryMaxBackoff
+	}
+	if policy.multiplier < 1 {
+		policy.multiplier = defaultRetryMultiplier
+	}
+	return policy
+}
+
+// backoff возвращает задержку перед повтором после попытки с номером attempt (с 1).
+// Половина задержки фиксирована, вторая половина случайна (equal jitter)
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.initialBackoff)
+	for i := 1; i < attempt; i++ {
+		delay *= p.multiplier
+		if delay >= float64(p.maxBackoff) {
+			delay = float64(p.maxBackoff)
+			break
+		}
+	}
+
+	half := time.Duration(delay / 2)
+	return half + rand.N(half+1)
+}
+
+// retryDelay определяет, можно ли повторить запрос после ошибки err, и возвращает задержку
+func (p retryPolicy) retryDelay(attempt int, err error) (time.Duration, bool) {
+	var tErr *transportError
+	if errors.As(err, &tErr) {
+		return p.backoff(attempt), true
+	}
+
+	var sErr *StatusError
+	if errors.As(err, &sErr) {
+		switch sErr.Code {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return p.backoff(attempt), true
+		case http.StatusTooManyRequests:
+			if sErr.retryAfter > 0 && sErr.retryAfter <= p.maxBackoff {
+				return sErr.retryAfter, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// parseRetryAfter разбирает значение заголовка Retry-After (секунды или HTTP-дата)
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// sleepContext ожидает delay или отмены контекста
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withRetry выполняет call, повторяя его при временных сбоях.
+// Повторы не выходят за дедлайн контекста запроса, target используется в логах
+func (ac *AccessClient) withRetry(ctx context.Context, target string, call func() error) error {
+	return ac.retry.do(ctx, ac.logger, target, call)
+}
+
+// do выполняет call по политике повторов
+func (p retryPolicy) do(ctx context.Context, logger Logger, target string, call func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= p.maxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		delay, ok := p.retryDelay(attempt, err)
+		if !ok {
+			return err
+		}
+
+		if deadline, hasDeadline := ctx.Deadline(); hasDeadline && time.Until(deadline) <= delay {
+			logger.Debug("Retry budget exhausted for %s, context deadline is too close", target)
+			return err
+		}
+
+		logger.Info("Retrying access check for %s in %v (attempt %d of %d): %v",
+			target, delay, attempt+1, p.maxAttempts, err)
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
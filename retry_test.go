@@ -0,0 +1,193 @@
+package locatorars
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedResponse ответ сценарного сервиса: статус 0 закрывает соединение без ответа
+type scriptedResponse struct {
+	status     int
+	retryAfter string
+}
+
+// scriptedServer отвечает по очереди ответами из сценария, после него разрешает доступ
+type scriptedServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []scriptedResponse
+	requests  int
+}
+
+func newScriptedServer(t *testing.T, responses ...scriptedResponse) *scriptedServer {
+	s := &scriptedServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		response := scriptedResponse{status: http.StatusOK}
+		if s.requests < len(s.responses) {
+			response = s.responses[s.requests]
+		}
+		s.requests++
+		s.mu.Unlock()
+
+		switch response.status {
+		case 0:
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		case http.StatusOK:
+			_, _ = w.Write([]byte(`{"allowed":true}`))
+		default:
+			if response.retryAfter != "" {
+				w.Header().Set("Retry-After", response.retryAfter)
+			}
+			w.WriteHeader(response.status)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *scriptedServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func retryClient(url string, retry RetryConfig) *AccessClient {
+	config := DefaultConfig()
+	config.URL = url
+	config.LogLevel = LogLevelNone
+	config.Retry = retry
+	return NewAccessClient(config)
+}
+
+func TestAccessClientRetry(t *testing.T) {
+	fast := RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Second}
+
+	tests := []struct {
+		name         string
+		responses    []scriptedResponse
+		wantRequests int
+		wantStatus   int
+	}{
+		{name: "service unavailable", responses: []scriptedResponse{{status: 503}, {status: 503}}, wantRequests: 3},
+		{name: "bad gateway", responses: []scriptedResponse{{status: 502}}, wantRequests: 2},
+		{name: "gateway timeout", responses: []scriptedResponse{{status: 504}}, wantRequests: 2},
+		{name: "transport error", responses: []scriptedResponse{{status: 0}}, wantRequests: 2},
+		{name: "attempt limit", responses: []scriptedResponse{{status: 503}, {status: 503}, {status: 503}, {status: 503}}, wantRequests: 3, wantStatus: 503},
+		{name: "bad request", responses: []scriptedResponse{{status: 400}}, wantRequests: 1, wantStatus: 400},
+		{name: "forbidden", responses: []scriptedResponse{{status: 403}}, wantRequests: 1, wantStatus: 403},
+		{name: "too many requests without Retry-After", responses: []scriptedResponse{{status: 429}}, wantRequests: 1, wantStatus: 429},
+		{name: "Retry-After above MaxBackoff", responses: []scriptedResponse{{status: 429, retryAfter: "5"}}, wantRequests: 1, wantStatus: 429},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newScriptedServer(t, tt.responses...)
+			_, err := retryClient(server.URL, fast).CheckAccessDetailed(context.Background(), "reports.view", "reports")
+
+			if tt.wantStatus == 0 && err != nil {
+				t.Fatalf("CheckAccessDetailed: %v", err)
+			}
+			if tt.wantStatus != 0 {
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || statusErr.Code != tt.wantStatus {
+					t.Fatalf("err %v, want status %d", err, tt.wantStatus)
+				}
+			}
+			if n := server.requestCount(); n != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", n, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestAccessClientRetryAfter(t *testing.T) {
+	server := newScriptedServer(t, scriptedResponse{status: 429, retryAfter: "1"})
+	client := retryClient(server.URL, RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+
+	startTime := time.Now()
+	if _, err := client.CheckAccessDetailed(context.Background(), "reports.view", "reports"); err != nil {
+		t.Fatalf("CheckAccessDetailed: %v", err)
+	}
+	if elapsed := time.Since(startTime); elapsed < time.Second {
+		t.Errorf("retried after %v, want the 1s from Retry-After", elapsed)
+	}
+	if n := server.requestCount(); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+}
+
+func TestAccessClientRetryStopsOnCancel(t *testing.T) {
+	server := newScriptedServer(t, scriptedResponse{status: 503}, scriptedResponse{status: 503})
+	client := retryClient(server.URL, RetryConfig{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	startTime := time.Now()
+	if _, err := client.CheckAccessDetailed(ctx, "reports.view", "reports"); err == nil {
+		t.Fatal("cancelled check returned no error")
+	}
+	if elapsed := time.Since(startTime); elapsed > time.Second {
+		t.Errorf("check took %v, want it to stop when the context is cancelled", elapsed)
+	}
+	if n := server.requestCount(); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "service unavailable", errs: []error{&StatusError{Code: 503}, &StatusError{Code: 503}}, wantCalls: 3},
+		{name: "attempt limit", errs: []error{&StatusError{Code: 503}, &StatusError{Code: 503}, &StatusError{Code: 503}}, wantCalls: 3, wantErr: true},
+		{name: "bad request", errs: []error{&StatusError{Code: 400}}, wantCalls: 1, wantErr: true},
+		{name: "unknown error", errs: []error{errors.New("decoding failed")}, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			checker := Decorate(CheckerFunc(func(ctx context.Context, action, entitlements string) (*AccessResponse, error) {
+				calls++
+				if calls <= len(tt.errs) {
+					return nil, tt.errs[calls-1]
+				}
+				return &AccessResponse{Action: action, Allowed: true}, nil
+			}), WithRetry(RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond}, nil))
+
+			_, err := checker.CheckAccessDetailed(context.Background(), "reports.view", "reports")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err %v, want error: %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("checker called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("seconds: %v, want 3s", got)
+	}
+	for _, value := range []string{"", "-1", "soon", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)} {
+		if got := parseRetryAfter(value); got != 0 {
+			t.Errorf("%q: %v, want 0", value, got)
+		}
+	}
+	if got := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); got <= 0 || got > time.Minute {
+		t.Errorf("HTTP date: %v, want up to 1m", got)
+	}
+}
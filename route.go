@@ -0,0 +1,94 @@
+package locatorars
+
+import (
+	"context"
+	"time"
+)
+
+// failurePolicy поведение маршрута при недоступности сервиса locator-ars
+type failurePolicy int
+
+const (
+	// failureDefault - политика из Config.AllowOnFailure
+	failureDefault failurePolicy = iota
+	failureOpen
+	failureClosed
+	failureServeStale
+)
+
+// RouteOption настраивает проверку доступа отдельного маршрута
+type RouteOption func(*routeOptions)
+
+// routeOptions параметры проверки маршрута
+type routeOptions struct {
+	failure failurePolicy
+}
+
+// newRouteOptions применяет опции маршрута
+func newRouteOptions(opts []RouteOption) routeOptions {
+	var options routeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// FailOpen пропускает запрос, если проверить доступ не удалось,
+// независимо от Config.AllowOnFailure. Подходит для некритичных маршрутов,
+// например панели мониторинга только для чтения
+func FailOpen() RouteOption {
+	return func(o *routeOptions) { o.failure = failureOpen }
+}
+
+// FailClosed отклоняет запрос, если проверить доступ не удалось,
+// независимо от Config.AllowOnFailure. Подходит для критичных маршрутов, например платежей
+func FailClosed() RouteOption {
+	return func(o *routeOptions) { o.failure = failureClosed }
+}
+
+// ServeStale при недоступности сервиса или таймауте применяет последнее решение, полученное
+// для того же действия и тех же Entitlements не раньше Config.LastKnownGood.GraceWindow назад.
+// Если такого решения нет или сервис ответил ошибкой, запрос отклоняется.
+// Опция работает и без LastKnownGood.Enabled, включающего такое поведение для всего клиента
+func ServeStale() RouteOption {
+	return func(o *routeOptions) { o.failure = failureServeStale }
+}
+
+// remember сохраняет решение маршрута с ServeStale для использования при недоступности сервиса.
+// Решение из кэша или хранилища клиента сохраняется со временем исходного ответа сервиса
+func (m *Middleware) remember(options routeOptions, label, entitlements string, decision AccessResponse) {
+	if options.failure != failureServeStale {
+		return
+	}
+	m.stale.remember(cacheKey(label, entitlements), decision)
+}
+
+// routeFailure формирует результат неудачной проверки с учетом политики маршрута
+func (m *Middleware) routeFailure(ctx context.Context, options routeOptions, target, label, entitlements string, err error) AuthResult {
+	if ctx.Err() != nil || options.failure == failureDefault {
+		return m.failure(ctx, target, err)
+	}
+
+	m.logger.Error("Error checking access for %s: %v", target, err)
+	switch options.failure {
+	case failureOpen:
+		m.logger.Info("Access allowed on failure due to route policy")
+		return AuthResult{Outcome: OutcomeFailOpen, Err: err}
+	case failureServeStale:
+		if !isServiceFailure(err) {
+			return AuthResult{Outcome: OutcomeError, Err: err}
+		}
+		decision, age, ok := m.stale.lookup(cacheKey(label, entitlements))
+		if !ok {
+			m.logger.Info("No stale decision for %s, access denied", target)
+			return AuthResult{Outcome: OutcomeError, Err: err}
+		}
+		m.logger.Info("Serving stale decision for %s confirmed %v ago: allowed=%v", target, age.Round(time.Millisecond), decision.Allowed)
+		if !decision.Allowed {
+			return AuthResult{Outcome: OutcomeDenied, Decision: &decision, Err: err}
+		}
+		return AuthResult{Outcome: OutcomeStale, Decision: &decision, Err: err}
+	default:
+		return AuthResult{Outcome: OutcomeError, Err: err}
+	}
+}
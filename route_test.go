@@ -0,0 +1,57 @@
+package locatorars_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+func TestServeStale(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		outcome locatorars.Outcome
+	}{
+		{name: "unavailable", status: http.StatusServiceUnavailable, outcome: locatorars.OutcomeStale},
+		{name: "too many requests", status: http.StatusTooManyRequests, outcome: locatorars.OutcomeStale},
+		{name: "bad request", status: http.StatusBadRequest, outcome: locatorars.OutcomeError},
+		{name: "not found", status: http.StatusNotFound, outcome: locatorars.OutcomeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := locatorarstest.NewServer(t)
+			m := s.Middleware()
+			ctx := context.Background()
+
+			s.Allow("reports.view")
+			if result := m.Authorize(ctx, "reports.view", "reports.view", locatorars.ServeStale()); result.Outcome != locatorars.OutcomeAllowed {
+				t.Fatalf("first check: outcome %v, err %v", result.Outcome, result.Err)
+			}
+
+			s.Reset()
+			s.Fail("reports.view", tt.status)
+			result := m.Authorize(ctx, "reports.view", "reports.view", locatorars.ServeStale())
+			if result.Outcome != tt.outcome {
+				t.Fatalf("outcome %v, want %v (err %v)", result.Outcome, tt.outcome, result.Err)
+			}
+			if result.Err == nil {
+				t.Fatal("expected the service error in the result")
+			}
+		})
+	}
+}
+
+func TestServeStaleWithoutDecision(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	m := s.Middleware()
+
+	s.Fail("reports.view", http.StatusServiceUnavailable)
+	result := m.Authorize(context.Background(), "reports.view", "reports.view", locatorars.ServeStale())
+	if result.Outcome != locatorars.OutcomeError {
+		t.Fatalf("outcome %v, want %v", result.Outcome, locatorars.OutcomeError)
+	}
+}
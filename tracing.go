@@ -0,0 +1,82 @@
+package locatorars
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName имя инструментирующей библиотеки в спанах
+const tracerName = "github.com/LT-Devs/locator-ars-go-lib"
+
+// Атрибуты спанов проверки доступа
+const (
+	AttrAction     = attribute.Key("locatorars.action")
+	AttrAllowed    = attribute.Key("locatorars.allowed")
+	AttrOutcome    = attribute.Key("locatorars.outcome")
+	AttrCacheHit   = attribute.Key("locatorars.cache_hit")
+	AttrStatusCode = attribute.Key("http.response.status_code")
+
+	// AttrCoalesced отмечает проверку, присоединившуюся к одновременному одинаковому запросу
+	AttrCoalesced = attribute.Key("locatorars.coalesced")
+
+	// AttrLastKnownGood отмечает решение, выданное из хранилища последних подтвержденных решений
+	AttrLastKnownGood = attribute.Key("locatorars.last_known_good")
+)
+
+// newTracer возвращает трассировщик из конфигурации или глобальный
+func newTracer(config Config) trace.Tracer {
+	provider := config.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(tracerName)
+}
+
+// newPropagator возвращает пропагатор контекста трассировки для запросов к сервису.
+// По умолчанию используется W3C Trace Context
+func newPropagator(config Config) propagation.TextMapPropagator {
+	if config.Propagator != nil {
+		return config.Propagator
+	}
+	return propagation.TraceContext{}
+}
+
+// injectTraceContext добавляет в исходящий запрос заголовки контекста трассировки
+func (ac *AccessClient) injectTraceContext(ctx context.Context, req *http.Request) {
+	ac.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
+
+// endCheckSpan записывает в спан проверки решение или ошибку и завершает его
+func endCheckSpan(span trace.Span, decision *AccessResponse, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if decision != nil {
+		span.SetAttributes(AttrAllowed.Bool(decision.Allowed))
+	}
+	span.End()
+}
+
+// endAuthorizeSpan записывает в спан итог проверки маршрута и завершает его
+func endAuthorizeSpan(span trace.Span, result AuthResult) {
+	span.SetAttributes(
+		AttrOutcome.String(result.Outcome.String()),
+		AttrAllowed.Bool(result.Allowed()),
+	)
+	switch result.Outcome {
+	case OutcomeError, OutcomeFailOpen, OutcomeStale, OutcomeTimeout:
+		if result.Err != nil {
+			span.RecordError(result.Err)
+		}
+		if result.Outcome != OutcomeFailOpen && result.Outcome != OutcomeStale {
+			span.SetStatus(codes.Error, result.Outcome.String())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,118 @@
+package locatorars_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	locatorars "github.com/LT-Devs/locator-ars-go-lib"
+	"github.com/LT-Devs/locator-ars-go-lib/locatorarstest"
+)
+
+// spanAttributes возвращает атрибуты экспортированного спана в виде карты
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracing(t *testing.T) {
+	s := locatorarstest.NewServer(t)
+	s.Allow("reports.view", "reports")
+	s.Deny("reports.edit")
+
+	// Синхронный экспорт: спан доступен в exporter сразу после завершения
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() { _ = provider.Shutdown(t.Context()) }()
+
+	config := s.Config()
+	config.TracerProvider = provider
+	config.Cache.Enabled = true
+	m := locatorars.NewMiddleware(config)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		action      string
+		wantAllowed bool
+		wantCached  bool
+	}{
+		{name: "allowed", action: "reports.view", wantAllowed: true},
+		{name: "allowed from cache", action: "reports.view", wantAllowed: true, wantCached: true},
+		{name: "denied", action: "reports.edit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			s.ResetRequests()
+
+			req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+			req.Header.Set(locatorars.EntitlementsHeader, "reports")
+			m.RequireActionHTTP(tt.action)(ok).ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := exporter.GetSpans()
+			if len(spans) != 2 {
+				t.Fatalf("recorded %d spans, want 2", len(spans))
+			}
+			check, authorize := spans[0], spans[1]
+			if check.Name != "locatorars.CheckAccess" || authorize.Name != "locatorars.Authorize" {
+				t.Fatalf("span names %q, %q, want locatorars.CheckAccess, locatorars.Authorize", check.Name, authorize.Name)
+			}
+			if check.Parent.SpanID() != authorize.SpanContext.SpanID() {
+				t.Error("check span is not a child of the authorize span")
+			}
+			if check.SpanKind != trace.SpanKindClient {
+				t.Errorf("check span kind %v, want client", check.SpanKind)
+			}
+
+			for _, span := range spans {
+				attrs := spanAttributes(span)
+				if got := attrs[locatorars.AttrAction].AsString(); got != tt.action {
+					t.Errorf("%s: action %q, want %q", span.Name, got, tt.action)
+				}
+				if got := attrs[locatorars.AttrAllowed].AsBool(); got != tt.wantAllowed {
+					t.Errorf("%s: allowed %v, want %v", span.Name, got, tt.wantAllowed)
+				}
+			}
+
+			attrs := spanAttributes(check)
+			if got := attrs[locatorars.AttrCacheHit].AsBool(); got != tt.wantCached {
+				t.Errorf("cache_hit %v, want %v", got, tt.wantCached)
+			}
+
+			requests := s.Requests()
+			if tt.wantCached {
+				if len(requests) != 0 {
+					t.Errorf("cached decision sent %d requests to the service", len(requests))
+				}
+				if _, ok := attrs[locatorars.AttrStatusCode]; ok {
+					t.Error("cached decision recorded a response status code")
+				}
+				return
+			}
+
+			if got := attrs[locatorars.AttrStatusCode].AsInt64(); got != http.StatusOK {
+				t.Errorf("status_code %d, want %d", got, http.StatusOK)
+			}
+			if len(requests) != 1 {
+				t.Fatalf("sent %d requests to the service, want 1", len(requests))
+			}
+			// Запрос к сервису продолжает трассу спана проверки
+			want := "00-" + check.SpanContext.TraceID().String() + "-" + check.SpanContext.SpanID().String() + "-01"
+			if got := requests[0].Header.Get("traceparent"); got != want {
+				t.Errorf("traceparent %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package locatorars
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// TLSConfig определяет параметры TLS-соединения с сервисом locator-ars
+type TLSConfig struct {
+	// Путь к PEM-файлу с сертификатами доверенных центров сертификации.
+	// Если не указан, используются системные сертификаты
+	CAFile string
+
+	// Пути к PEM-файлам клиентского сертификата и ключа для mTLS.
+	// Указываются вместе
+	CertFile string
+	KeyFile  string
+
+	// Имя сервера для проверки сертификата, если оно отличается от хоста в URL
+	ServerName string
+}
+
+// isZero возвращает true, если ни один параметр TLS не задан
+func (tc TLSConfig) isZero() bool {
+	return tc == TLSConfig{}
+}
+
+// NewHTTPClient создает HTTP-клиент для запросов к locator-ars в соответствии с конфигурацией.
+// Позволяет проверить настройки TLS при старте приложения: NewAccessClient
+// при ошибке не паникует, а возвращает ее при каждой проверке доступа
+func NewHTTPClient(config Config) (*http.Client, error) {
+	if config.HTTPClient != nil {
+		return config.HTTPClient, nil
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	transport := config.Transport
+	if !config.TLS.isZero() {
+		if transport != nil {
+			return nil, fmt.Errorf("TLS and Transport options are mutually exclusive")
+		}
+
+		tlsConfig, err := buildTLSConfig(config.TLS)
+		if err != nil {
+			return nil, err
+		}
+
+		defaultTransport := http.DefaultTransport.(*http.Transport).Clone()
+		defaultTransport.TLSClientConfig = tlsConfig
+		transport = defaultTransport
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}, nil
+}
+
+// buildTLSConfig загружает сертификаты и формирует tls.Config
+func buildTLSConfig(config TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: config.ServerName,
+	}
+
+	if config.CAFile != "" {
+		caPEM, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificates found in CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		if config.CertFile == "" || config.KeyFile == "" {
+			return nil, fmt.Errorf("both CertFile and KeyFile must be set for client certificate")
+		}
+
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
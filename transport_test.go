@@ -0,0 +1,229 @@
+package locatorars
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePEM записывает PEM-блок во временный файл теста и возвращает путь к нему
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// serverCAFile сохраняет сертификат TLS-сервера как доверенный CA
+func serverCAFile(t *testing.T, server *httptest.Server) string {
+	return writePEM(t, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+}
+
+// clientCertificate выпускает клиентский сертификат, подписанный новым CA.
+// Возвращает пул с этим CA для сервера и пути к файлам сертификата и ключа
+func clientCertificate(t *testing.T) (*x509.CertPool, string, string) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "locator-ars test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "locator-ars test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, caCert, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return pool, writePEM(t, "client.pem", "CERTIFICATE", clientDER), writePEM(t, "client.key", "PRIVATE KEY", keyDER)
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestNewHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(okHandler())
+	defer server.Close()
+
+	client, err := NewHTTPClient(Config{TLS: TLSConfig{CAFile: serverCAFile(t, server)}})
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with CA bundle: %v", err)
+	}
+	resp.Body.Close()
+
+	// Без CA сертификат тестового сервера не проходит проверку
+	client, err = NewHTTPClient(Config{})
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("request without CA bundle succeeded")
+	}
+}
+
+func TestNewHTTPClientMutualTLS(t *testing.T) {
+	clientCAs, certFile, keyFile := clientCertificate(t)
+
+	server := httptest.NewUnstartedServer(okHandler())
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	caFile := serverCAFile(t, server)
+
+	client, err := NewHTTPClient(Config{TLS: TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}})
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with client certificate: %v", err)
+	}
+	resp.Body.Close()
+
+	client, err = NewHTTPClient(Config{TLS: TLSConfig{CAFile: caFile}})
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("request without client certificate succeeded")
+	}
+}
+
+func TestNewHTTPClientInvalidTLS(t *testing.T) {
+	_, certFile, keyFile := clientCertificate(t)
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "transport and TLS", config: Config{Transport: http.DefaultTransport, TLS: TLSConfig{ServerName: "locator-ars"}}},
+		{name: "missing CA file", config: Config{TLS: TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}}},
+		{name: "CA file without certificates", config: Config{TLS: TLSConfig{CAFile: notPEM}}},
+		{name: "certificate without key", config: Config{TLS: TLSConfig{CertFile: certFile}}},
+		{name: "key without certificate", config: Config{TLS: TLSConfig{KeyFile: keyFile}}},
+		{name: "mismatched certificate and key", config: Config{TLS: TLSConfig{CertFile: keyFile, KeyFile: certFile}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHTTPClient(tt.config); err == nil {
+				t.Fatal("NewHTTPClient accepted an invalid configuration")
+			}
+
+			// AccessClient не паникует, а возвращает ошибку при каждой проверке
+			config := tt.config
+			config.LogLevel = LogLevelNone
+			if _, err := NewAccessClient(config).CheckAccessDetailed(context.Background(), "reports.view", "reports"); err == nil {
+				t.Fatal("AccessClient with invalid TLS configuration returned no error")
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClient(Config{Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+	if client.Timeout != 50*time.Millisecond {
+		t.Fatalf("client timeout %v, want 50ms", client.Timeout)
+	}
+
+	config := DefaultConfig()
+	config.URL = server.URL
+	config.Timeout = 50 * time.Millisecond
+	config.LogLevel = LogLevelNone
+
+	startTime := time.Now()
+	_, err = NewAccessClient(config).CheckAccessDetailed(context.Background(), "reports.view", "reports")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("err %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(startTime); elapsed > 500*time.Millisecond {
+		t.Fatalf("check took %v, want it to stop at the 50ms timeout", elapsed)
+	}
+}
+
+func TestNewHTTPClientDefaults(t *testing.T) {
+	client, err := NewHTTPClient(Config{})
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+	if client.Timeout != defaultTimeout {
+		t.Errorf("default timeout %v, want %v", client.Timeout, defaultTimeout)
+	}
+
+	custom := &http.Client{}
+	if client, _ := NewHTTPClient(Config{HTTPClient: custom, TLS: TLSConfig{CAFile: "ignored.pem"}}); client != custom {
+		t.Error("HTTPClient from config was not used as is")
+	}
+}